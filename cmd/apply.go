@@ -12,7 +12,11 @@ import (
 var (
 	applyExample = `
 * Apply local schema file
-  hammer apply spanner://projects/projectId/instances/instanceId/databases/databaseName /path/to/file`
+  hammer apply spanner://projects/projectId/instances/instanceId/databases/databaseName /path/to/file
+
+* Apply local schema files in a directory or matching a pattern
+  hammer apply spanner://projects/projectId/instances/instanceId/databases/databaseName /path/to/dir
+  hammer apply spanner://projects/projectId/instances/instanceId/databases/databaseName 'file:///path/to/dir/*.sql'`
 
 	applyCmd = &cobra.Command{
 		Use:     "apply DATABASE SOURCE",
@@ -42,6 +46,22 @@ var (
 			if err != nil {
 				return err
 			}
+			protoDescriptorsFile, err := cmd.Flags().GetString("proto-descriptors-file")
+			if err != nil {
+				return err
+			}
+			rebuildTable, err := cmd.Flags().GetBool("rebuild-table")
+			if err != nil {
+				return err
+			}
+			convertColumnType, err := cmd.Flags().GetBool("convert-column-type")
+			if err != nil {
+				return err
+			}
+			renames, err := cmd.Flags().GetStringArray("rename")
+			if err != nil {
+				return err
+			}
 			ddlOption := &hammer.DDLOption{
 				IgnoreAlterDatabase: ignoreAlterDatabase,
 				IgnoreChangeStreams: ignoreChangeStreams,
@@ -68,8 +88,24 @@ var (
 			if err != nil {
 				return err
 			}
+			if protoDescriptorsFile != "" {
+				if sourceDDL.ProtoDescriptors, err = hammer.ReadProtoDescriptors(protoDescriptorsFile); err != nil {
+					return err
+				}
+			}
+
+			for _, r := range renames {
+				rename, err := hammer.ParseRename(r)
+				if err != nil {
+					return err
+				}
+				sourceDDL.Renames = append(sourceDDL.Renames, rename)
+			}
 
-			ddl, err := hammer.Diff(databaseDDL, sourceDDL)
+			ddl, err := hammer.Diff(databaseDDL, sourceDDL, &hammer.DiffOption{
+				RebuildTable:      rebuildTable,
+				ConvertColumnType: convertColumnType,
+			})
 			if err != nil {
 				return err
 			}
@@ -89,6 +125,10 @@ func init() {
 	applyCmd.Flags().Bool("ignore-alter-database", false, "ignore alter database statements")
 	applyCmd.Flags().Bool("ignore-change-streams", false, "ignore change streams statements")
 	applyCmd.Flags().Bool("ignore-models", false, "ignore model statements")
+	applyCmd.Flags().String("proto-descriptors-file", "", "path to the FileDescriptorSet of the proto bundle in SOURCE")
+	applyCmd.Flags().Bool("rebuild-table", false, "copy rows into a rebuilt table instead of dropping tables whose primary key or interleave parent changed")
+	applyCmd.Flags().Bool("convert-column-type", false, "convert the values of columns whose type cannot be altered in place instead of dropping them")
+	applyCmd.Flags().StringArray("rename", nil, "rename a table (OLD_TABLE=NEW_TABLE) or a column (TABLE.OLD_COLUMN=NEW_COLUMN) instead of dropping it")
 
 	rootCmd.AddCommand(applyCmd)
 }
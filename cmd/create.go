@@ -44,6 +44,10 @@ var (
 			if err != nil {
 				return err
 			}
+			protoDescriptorsFile, err := cmd.Flags().GetString("proto-descriptors-file")
+			if err != nil {
+				return err
+			}
 			ddlOption := &hammer.DDLOption{
 				IgnoreAlterDatabase: ignoreAlterDatabase,
 				IgnoreChangeStreams: ignoreChangeStreams,
@@ -66,6 +70,11 @@ var (
 			if err != nil {
 				return err
 			}
+			if protoDescriptorsFile != "" {
+				if ddl.ProtoDescriptors, err = hammer.ReadProtoDescriptors(protoDescriptorsFile); err != nil {
+					return err
+				}
+			}
 			return database.Create(ctx, ddl)
 		},
 	}
@@ -75,6 +84,7 @@ func init() {
 	createCmd.Flags().Bool("ignore-alter-database", false, "ignore alter database statements")
 	createCmd.Flags().Bool("ignore-change-streams", false, "ignore change streams statements")
 	createCmd.Flags().Bool("ignore-models", false, "ignore model statements")
+	createCmd.Flags().String("proto-descriptors-file", "", "path to the FileDescriptorSet of the proto bundle in SOURCE")
 
 	rootCmd.AddCommand(createCmd)
 }
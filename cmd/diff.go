@@ -17,6 +17,9 @@ var (
 * Compare local file against spanner schema
   hammer diff /path/to/file spanner://projects/projectId/instances/instanceId/databases/databaseName
 
+* Compare local file against the file at a git revision
+  hammer diff git:origin/main:path/to/file /path/to/file
+
 * Compare spanner schema against spanner schema
   hammer diff spanner://projects/projectId/instances/instanceId/databases/databaseName1 spanner://projects/projectId/instances/instanceId/databases/databaseName2`
 
@@ -48,6 +51,22 @@ var (
 			if err != nil {
 				return err
 			}
+			protoDescriptorsFile, err := cmd.Flags().GetString("proto-descriptors-file")
+			if err != nil {
+				return err
+			}
+			rebuildTable, err := cmd.Flags().GetBool("rebuild-table")
+			if err != nil {
+				return err
+			}
+			convertColumnType, err := cmd.Flags().GetBool("convert-column-type")
+			if err != nil {
+				return err
+			}
+			renames, err := cmd.Flags().GetStringArray("rename")
+			if err != nil {
+				return err
+			}
 			ddlOption := &hammer.DDLOption{
 				IgnoreAlterDatabase: ignoreAlterDatabase,
 				IgnoreChangeStreams: ignoreChangeStreams,
@@ -71,8 +90,24 @@ var (
 			if err != nil {
 				return err
 			}
+			if protoDescriptorsFile != "" {
+				if ddl2.ProtoDescriptors, err = hammer.ReadProtoDescriptors(protoDescriptorsFile); err != nil {
+					return err
+				}
+			}
+
+			for _, r := range renames {
+				rename, err := hammer.ParseRename(r)
+				if err != nil {
+					return err
+				}
+				ddl2.Renames = append(ddl2.Renames, rename)
+			}
 
-			ddl, err := hammer.Diff(ddl1, ddl2)
+			ddl, err := hammer.Diff(ddl1, ddl2, &hammer.DiffOption{
+				RebuildTable:      rebuildTable,
+				ConvertColumnType: convertColumnType,
+			})
 			if err != nil {
 				return err
 			}
@@ -88,6 +123,10 @@ func init() {
 	diffCmd.Flags().Bool("ignore-alter-database", false, "ignore alter database statements")
 	diffCmd.Flags().Bool("ignore-change-streams", false, "ignore change streams statements")
 	diffCmd.Flags().Bool("ignore-models", false, "ignore model statements")
+	diffCmd.Flags().String("proto-descriptors-file", "", "path to the FileDescriptorSet of the proto bundle in SOURCE2")
+	diffCmd.Flags().Bool("rebuild-table", false, "copy rows into a rebuilt table instead of dropping tables whose primary key or interleave parent changed")
+	diffCmd.Flags().Bool("convert-column-type", false, "convert the values of columns whose type cannot be altered in place instead of dropping them")
+	diffCmd.Flags().StringArray("rename", nil, "rename a table (OLD_TABLE=NEW_TABLE) or a column (TABLE.OLD_COLUMN=NEW_COLUMN) instead of dropping it")
 
 	rootCmd.AddCommand(diffCmd)
 }
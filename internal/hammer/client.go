@@ -3,19 +3,37 @@ package hammer
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 
 	"cloud.google.com/go/spanner"
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
 	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/cloudspannerecosystem/memefish/ast"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// copyTableBatchMutations is the number of cells written in each commit of CopyTable.
+// It is kept well under the mutation limit to leave room for the index entries.
+const copyTableBatchMutations = 20000
+
 type Client struct {
 	database string
 	client   *spanner.Client
 	admin    *database.DatabaseAdminClient
+	progress io.Writer
+	// copyBatchMutations is the number of cells written in each commit of CopyTable.
+	copyBatchMutations int
 }
 
 func NewClient(ctx context.Context, uri string) (*Client, error) {
@@ -24,10 +42,15 @@ func NewClient(ctx context.Context, uri string) (*Client, error) {
 		return nil, err
 	}
 	db := u.Host + u.Path
-
-	opts := []option.ClientOption{}
-	if credentials := u.Query().Get("credentials"); credentials != "" {
-		opts = append(opts, option.WithCredentialsFile(credentials))
+	params, err := parseConnectionParams(u.Query())
+	if err != nil {
+		return nil, err
+	}
+	opts := params.clientOptions()
+	if params.createInstance {
+		if err := createEmulatorInstance(ctx, db, opts); err != nil {
+			return nil, err
+		}
 	}
 	client, err := spanner.NewClient(ctx, db, opts...)
 	if err != nil {
@@ -42,17 +65,111 @@ func NewClient(ctx context.Context, uri string) (*Client, error) {
 		database: db,
 		client:   client,
 		admin:    admin,
+		progress: os.Stderr,
+
+		copyBatchMutations: copyTableBatchMutations,
 	}, nil
 }
 
-func (c *Client) GetDatabaseDDL(ctx context.Context) (string, error) {
+// connectionParams are the query parameters of the DSN.
+type connectionParams struct {
+	credentials    string
+	emulator       string
+	createInstance bool
+}
+
+func parseConnectionParams(query url.Values) (connectionParams, error) {
+	params := connectionParams{
+		credentials: query.Get("credentials"),
+		emulator:    query.Get("emulator"),
+	}
+	if params.credentials != "" && params.emulator != "" {
+		return connectionParams{}, fmt.Errorf("credentials cannot be used with emulator, which is connected without authentication")
+	}
+	if createInstance := query.Get("create_instance"); createInstance != "" {
+		if params.emulator == "" {
+			return connectionParams{}, fmt.Errorf("create_instance can be used only with emulator")
+		}
+		create, err := strconv.ParseBool(createInstance)
+		if err != nil {
+			return connectionParams{}, fmt.Errorf("invalid create_instance %q: %s", createInstance, err)
+		}
+		params.createInstance = create
+	}
+	return params, nil
+}
+
+func (p connectionParams) clientOptions() []option.ClientOption {
+	opts := []option.ClientOption{}
+	if p.credentials != "" {
+		opts = append(opts, option.WithCredentialsFile(p.credentials))
+	}
+	if p.emulator != "" {
+		opts = append(opts,
+			option.WithEndpoint(p.emulator),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		)
+	}
+	return opts
+}
+
+// parseDatabasePath returns the project and the instance of the database path
+// in the format "projects/{projectId}/instances/{instanceId}/databases/{databaseName}".
+func parseDatabasePath(db string) (project, instance string, err error) {
+	parts := strings.Split(db, "/")
+	if len(parts) != 6 || parts[0] != "projects" || parts[2] != "instances" || parts[4] != "databases" || parts[1] == "" || parts[3] == "" || parts[5] == "" {
+		return "", "", fmt.Errorf("invalid database %s: must be projects/{projectId}/instances/{instanceId}/databases/{databaseName}", db)
+	}
+	return parts[1], parts[3], nil
+}
+
+// createEmulatorInstance creates the instance of the database on the emulator, if it does not exist.
+func createEmulatorInstance(ctx context.Context, db string, opts []option.ClientOption) error {
+	project, instanceID, err := parseDatabasePath(db)
+	if err != nil {
+		return err
+	}
+	admin, err := instance.NewInstanceAdminClient(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	op, err := admin.CreateInstance(ctx, emulatorInstanceRequest(project, instanceID))
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create instance %s: %s", instanceID, err)
+	}
+	if _, err := op.Wait(ctx); err != nil && status.Code(err) != codes.AlreadyExists {
+		return fmt.Errorf("failed to create instance %s: %s", instanceID, err)
+	}
+	return nil
+}
+
+// emulatorInstanceRequest returns the request creating the instance on the emulator, which provides a single instance config.
+func emulatorInstanceRequest(project, instanceID string) *instancepb.CreateInstanceRequest {
+	return &instancepb.CreateInstanceRequest{
+		Parent:     fmt.Sprintf("projects/%s", project),
+		InstanceId: instanceID,
+		Instance: &instancepb.Instance{
+			Config:      fmt.Sprintf("projects/%s/instanceConfigs/emulator-config", project),
+			DisplayName: instanceID,
+			NodeCount:   1,
+		},
+	}
+}
+
+func (c *Client) GetDatabaseDDL(ctx context.Context) (string, []byte, error) {
 	response, err := c.admin.GetDatabaseDdl(ctx, &databasepb.GetDatabaseDdlRequest{
 		Database: c.database,
 	})
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return strings.Join(response.Statements, ";\n"), nil
+	return strings.Join(response.Statements, ";\n"), response.ProtoDescriptors, nil
 }
 
 func (c *Client) CreateDatabase(ctx context.Context, ddl DDL) error {
@@ -62,9 +179,10 @@ func (c *Client) CreateDatabase(ctx context.Context, ddl DDL) error {
 		stmts[i] = stmt.SQL()
 	}
 	op, err := c.admin.CreateDatabase(ctx, &databasepb.CreateDatabaseRequest{
-		Parent:          fmt.Sprintf("projects/%s/instances/%s", parts[1], parts[3]),
-		CreateStatement: fmt.Sprintf("CREATE DATABASE `%s`", parts[5]),
-		ExtraStatements: stmts,
+		Parent:           fmt.Sprintf("projects/%s/instances/%s", parts[1], parts[3]),
+		CreateStatement:  fmt.Sprintf("CREATE DATABASE `%s`", parts[5]),
+		ExtraStatements:  stmts,
+		ProtoDescriptors: ddl.ProtoDescriptors,
 	})
 	if err != nil {
 		return err
@@ -80,28 +198,29 @@ func (c *Client) ApplyDatabaseDDL(ctx context.Context, ddl DDL) error {
 			stmts = append(stmts, stmt.SQL())
 		} else {
 			if len(stmts) > 0 {
-				if err := c.updateDatabaseDDL(ctx, stmts); err != nil {
+				if err := c.updateDatabaseDDL(ctx, stmts, ddl.ProtoDescriptors); err != nil {
 					return err
 				}
 				stmts = stmts[:0]
 			}
-			if err := c.partitionedUpdate(ctx, stmt.SQL()); err != nil {
+			if err := c.executeStatement(ctx, stmt); err != nil {
 				return err
 			}
 		}
 	}
 	if len(stmts) > 0 {
-		if err := c.updateDatabaseDDL(ctx, stmts); err != nil {
+		if err := c.updateDatabaseDDL(ctx, stmts, ddl.ProtoDescriptors); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (c *Client) updateDatabaseDDL(ctx context.Context, stmts []string) error {
+func (c *Client) updateDatabaseDDL(ctx context.Context, stmts []string, protoDescriptors []byte) error {
 	op, err := c.admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
-		Database:   c.database,
-		Statements: stmts,
+		Database:         c.database,
+		Statements:       stmts,
+		ProtoDescriptors: protoDescriptors,
 	})
 	if err != nil {
 		return err
@@ -109,6 +228,158 @@ func (c *Client) updateDatabaseDDL(ctx context.Context, stmts []string) error {
 	return op.Wait(ctx)
 }
 
+func (c *Client) executeStatement(ctx context.Context, stmt Statement) error {
+	switch s := stmt.(type) {
+	case CopyTable:
+		return c.copyTable(ctx, s)
+	default:
+		return c.partitionedUpdate(ctx, stmt.SQL())
+	}
+}
+
+func (c *Client) copyTable(ctx context.Context, stmt CopyTable) error {
+	from := identsToComparable(stmt.From.Idents...)
+	to := identsToComparable(stmt.To.Idents...)
+
+	columns := make([]string, len(stmt.Columns))
+	for i, col := range stmt.Columns {
+		columns[i] = col.Name
+	}
+	readColumns := columns
+	keyIndexes := make([]int, len(stmt.Keys))
+	resumable := true
+	for i, key := range stmt.Keys {
+		keyIndexes[i] = -1
+		for j, col := range columns {
+			if strings.EqualFold(col, key.Name.Name) {
+				keyIndexes[i] = j
+				break
+			}
+		}
+		if keyIndexes[i] < 0 {
+			resumable = false
+			keyIndexes[i] = len(readColumns)
+			readColumns = append(readColumns, key.Name.Name)
+		}
+	}
+
+	var last spanner.Key
+	if resumable {
+		key, err := c.lastCopiedKey(ctx, stmt)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			fmt.Fprintf(c.progress, "resuming copy from %s to %s after key %s\n", from, to, key)
+		}
+		last = key
+	}
+
+	batchSize := c.copyBatchMutations / len(readColumns)
+	if batchSize == 0 {
+		batchSize = 1
+	}
+	copied := 0
+	for {
+		keys := spanner.AllKeys()
+		if last != nil {
+			keys = spanner.KeyRange{Start: last, End: spanner.Key{}, Kind: spanner.OpenClosed}
+		}
+		var mutations []*spanner.Mutation
+		iter := c.client.Single().ReadWithOptions(ctx, from, keys, readColumns, &spanner.ReadOptions{Limit: batchSize})
+		err := iter.Do(func(row *spanner.Row) error {
+			values := make([]interface{}, len(readColumns))
+			for i := range readColumns {
+				var v spanner.GenericColumnValue
+				if err := row.Column(i, &v); err != nil {
+					return err
+				}
+				values[i] = v
+			}
+			key := make(spanner.Key, len(keyIndexes))
+			for i, j := range keyIndexes {
+				part, err := keyPart(values[j].(spanner.GenericColumnValue))
+				if err != nil {
+					return err
+				}
+				key[i] = part
+			}
+			mutations = append(mutations, spanner.InsertOrUpdate(to, columns, values[:len(columns)]))
+			last = key
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %s", from, err)
+		}
+		if len(mutations) == 0 {
+			break
+		}
+		if _, err := c.client.Apply(ctx, mutations); err != nil {
+			return fmt.Errorf("failed to copy rows from %s to %s: %s", from, to, err)
+		}
+		copied += len(mutations)
+		fmt.Fprintf(c.progress, "copied %d rows from %s to %s\n", copied, from, to)
+		if len(mutations) < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// lastCopiedKey returns the primary key of the source table of the last row copied into the shadow table,
+// or nil if no rows have been copied yet.
+func (c *Client) lastCopiedKey(ctx context.Context, stmt CopyTable) (spanner.Key, error) {
+	// the rows are copied in the order of the primary key of the source table,
+	// so the last copied row is the first one in the reverse order of it.
+	keys := make([]string, len(stmt.Keys))
+	orders := make([]string, len(stmt.Keys))
+	for i, key := range stmt.Keys {
+		keys[i] = key.Name.SQL()
+		if key.Dir == ast.DirectionDesc {
+			orders[i] = keys[i] + " ASC"
+		} else {
+			orders[i] = keys[i] + " DESC"
+		}
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT 1", strings.Join(keys, ", "), stmt.To.SQL(), strings.Join(orders, ", "))
+
+	var last spanner.Key
+	err := c.client.Single().Query(ctx, spanner.Statement{SQL: sql}).Do(func(row *spanner.Row) error {
+		last = make(spanner.Key, row.Size())
+		for i := range last {
+			var v spanner.GenericColumnValue
+			if err := row.Column(i, &v); err != nil {
+				return err
+			}
+			part, err := keyPart(v)
+			if err != nil {
+				return err
+			}
+			last[i] = part
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", stmt.To.SQL(), err)
+	}
+	return last, nil
+}
+
+// keyPart converts a column value into a key part that is encoded into the same value.
+func keyPart(v spanner.GenericColumnValue) (interface{}, error) {
+	switch k := v.Value.GetKind().(type) {
+	case *structpb.Value_NullValue:
+		return spanner.NullString{}, nil
+	case *structpb.Value_StringValue:
+		return k.StringValue, nil
+	case *structpb.Value_NumberValue:
+		return k.NumberValue, nil
+	case *structpb.Value_BoolValue:
+		return k.BoolValue, nil
+	}
+	return nil, fmt.Errorf("unsupported key value: %v", v.Value)
+}
+
 func (c *Client) partitionedUpdate(ctx context.Context, stmt string) error {
 	_, err := c.client.PartitionedUpdate(ctx, spanner.Statement{SQL: stmt})
 	return err
@@ -116,7 +387,7 @@ func (c *Client) partitionedUpdate(ctx context.Context, stmt string) error {
 
 func (c *Client) isUpdateDatabaseStatement(stmt Statement) bool {
 	switch stmt.(type) {
-	case Update:
+	case Update, CopyTable, ConvertColumn:
 		return false
 	default:
 		return true
@@ -0,0 +1,363 @@
+package hammer_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"cloud.google.com/go/spanner/spannertest"
+	"cloud.google.com/go/spanner/spansql"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/daichirata/hammer/internal/hammer"
+)
+
+func TestParseConnectionParams(t *testing.T) {
+	emulatorOptions := []option.ClientOption{
+		option.WithEndpoint("localhost:9010"),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+	values := []struct {
+		query          string
+		credentials    string
+		emulator       string
+		createInstance bool
+		options        []option.ClientOption
+		err            string
+	}{
+		{
+			query:   "",
+			options: []option.ClientOption{},
+		},
+		{
+			query:       "credentials=/path/to/file.json",
+			credentials: "/path/to/file.json",
+			options:     []option.ClientOption{option.WithCredentialsFile("/path/to/file.json")},
+		},
+		{
+			query:    "emulator=localhost:9010",
+			emulator: "localhost:9010",
+			options:  emulatorOptions,
+		},
+		{
+			query:          "emulator=localhost:9010&create_instance=true",
+			emulator:       "localhost:9010",
+			createInstance: true,
+			options:        emulatorOptions,
+		},
+		{
+			query:    "emulator=localhost:9010&create_instance=false",
+			emulator: "localhost:9010",
+			options:  emulatorOptions,
+		},
+		{
+			query: "emulator=localhost:9010&create_instance=yes",
+			err:   `invalid create_instance "yes"`,
+		},
+		{
+			query: "credentials=/path/to/file.json&emulator=localhost:9010",
+			err:   "credentials cannot be used with emulator",
+		},
+		{
+			query: "create_instance=true",
+			err:   "create_instance can be used only with emulator",
+		},
+	}
+	for _, v := range values {
+		t.Run(v.query, func(t *testing.T) {
+			query, err := url.ParseQuery(v.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			credentials, emulator, createInstance, options, err := hammer.ParseConnectionParams(query)
+			if v.err != "" {
+				if err == nil || !strings.Contains(err.Error(), v.err) {
+					t.Fatalf("got %v want error containing %q", err, v.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if credentials != v.credentials || emulator != v.emulator || createInstance != v.createInstance {
+				t.Errorf("got (%q, %q, %v) want (%q, %q, %v)", credentials, emulator, createInstance, v.credentials, v.emulator, v.createInstance)
+			}
+			// gRPC dial options are functions, which are compared by their types.
+			if diff := cmp.Diff(v.options, options,
+				cmp.Exporter(func(reflect.Type) bool { return true }),
+				cmp.Comparer(func(x, y grpc.DialOption) bool { return reflect.TypeOf(x) == reflect.TypeOf(y) }),
+			); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseConnectionParamsConnectEmulator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := spannertest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatalf("failed to start fake server: %v", err)
+	}
+	defer srv.Close()
+	srv.SetLogger(t.Logf)
+
+	_, _, _, options, err := hammer.ParseConnectionParams(url.Values{"emulator": {srv.Addr}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the client connects to the emulator only if the options set its endpoint, disable authentication and use an insecure transport.
+	client, err := spanner.NewClient(ctx, "projects/p/instances/i/databases/d", options...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+	if err := client.Single().Query(ctx, spanner.NewStatement("SELECT 1")).Do(func(*spanner.Row) error { return nil }); err != nil {
+		t.Fatalf("failed to query the emulator: %v", err)
+	}
+}
+
+func TestEmulatorInstanceRequest(t *testing.T) {
+	expected := &instancepb.CreateInstanceRequest{
+		Parent:     "projects/p1",
+		InstanceId: "i1",
+		Instance: &instancepb.Instance{
+			Config:      "projects/p1/instanceConfigs/emulator-config",
+			DisplayName: "i1",
+			NodeCount:   1,
+		},
+	}
+	if diff := cmp.Diff(expected, hammer.EmulatorInstanceRequest("p1", "i1"), protocmp.Transform()); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
+
+func TestParseDatabasePath(t *testing.T) {
+	values := []struct {
+		db       string
+		project  string
+		instance string
+		err      bool
+	}{
+		{
+			db:       "projects/p1/instances/i1/databases/d1",
+			project:  "p1",
+			instance: "i1",
+		},
+		{
+			db:  "projects/p1/instances/i1",
+			err: true,
+		},
+		{
+			db:  "projects/p1/instances//databases/d1",
+			err: true,
+		},
+		{
+			db:  "instances/i1/projects/p1/databases/d1",
+			err: true,
+		},
+	}
+	for _, v := range values {
+		t.Run(v.db, func(t *testing.T) {
+			project, instance, err := hammer.ParseDatabasePath(v.db)
+			if v.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if project != v.project || instance != v.instance {
+				t.Errorf("got (%q, %q) want (%q, %q)", project, instance, v.project, v.instance)
+			}
+		})
+	}
+}
+
+// newFakeClient returns a client of a database on a fake server, which has the tables t1 and its shadow table.
+func newFakeClient(t *testing.T, copyBatchMutations int) (*hammer.Client, *spanner.Client, *bytes.Buffer) {
+	t.Helper()
+	ctx := context.Background()
+
+	srv, err := spannertest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatalf("failed to start fake server: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	srv.SetLogger(t.Logf)
+	ddl, err := spansql.ParseDDL("fake", `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX),
+) PRIMARY KEY(t1_1);
+CREATE TABLE t1__hammer_rebuild (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX),
+) PRIMARY KEY(t1_2, t1_1);
+`)
+	if err != nil {
+		t.Fatalf("failed to parse ddl: %v", err)
+	}
+	if err := srv.UpdateDDL(ddl); err != nil {
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	client, err := spanner.NewClient(ctx, "projects/p/instances/i/databases/d",
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect to fake server: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	progress := &bytes.Buffer{}
+	return hammer.NewTestClient(client, progress, copyBatchMutations), client, progress
+}
+
+func insertRows(t *testing.T, client *spanner.Client, table string, ids ...int64) {
+	t.Helper()
+	var mutations []*spanner.Mutation
+	for _, id := range ids {
+		mutations = append(mutations, spanner.Insert(table, []string{"t1_1", "t1_2"}, []interface{}{id, "name"}))
+	}
+	if _, err := client.Apply(context.Background(), mutations); err != nil {
+		t.Fatalf("failed to insert rows: %v", err)
+	}
+}
+
+func readIDs(t *testing.T, client *spanner.Client, table string) []int64 {
+	t.Helper()
+	var ids []int64
+	iter := client.Single().Query(context.Background(), spanner.Statement{SQL: "SELECT t1_1 FROM " + table + " ORDER BY t1_1"})
+	err := iter.Do(func(row *spanner.Row) error {
+		var id int64
+		if err := row.Column(0, &id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read rows: %v", err)
+	}
+	return ids
+}
+
+func copyTableStatement(dir ast.Direction) hammer.CopyTable {
+	return hammer.CopyTable{
+		From:    &ast.Path{Idents: []*ast.Ident{{Name: "t1"}}},
+		To:      &ast.Path{Idents: []*ast.Ident{{Name: "t1__hammer_rebuild"}}},
+		Columns: []*ast.Ident{{Name: "t1_1"}, {Name: "t1_2"}},
+		Keys:    []*ast.IndexKey{{Name: &ast.Ident{Name: "t1_1"}, Dir: dir}},
+	}
+}
+
+func TestClientCopyTable(t *testing.T) {
+	values := []struct {
+		name     string
+		copied   []int64
+		progress string
+	}{
+		{
+			name: "copy rows in batches",
+			progress: `copied 2 rows from t1 to t1__hammer_rebuild
+copied 4 rows from t1 to t1__hammer_rebuild
+copied 5 rows from t1 to t1__hammer_rebuild
+`,
+		},
+		{
+			name:   "resume copy after the last copied row",
+			copied: []int64{1, 2, 3},
+			progress: `resuming copy from t1 to t1__hammer_rebuild after key ("3")
+copied 2 rows from t1 to t1__hammer_rebuild
+`,
+		},
+		{
+			name:   "resume copy of all rows copied",
+			copied: []int64{1, 2, 3, 4, 5},
+			progress: `resuming copy from t1 to t1__hammer_rebuild after key ("5")
+`,
+		},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			// 2 columns in each batch of 4 cells
+			c, client, progress := newFakeClient(t, 4)
+			insertRows(t, client, "t1", 1, 2, 3, 4, 5)
+			if len(v.copied) > 0 {
+				insertRows(t, client, "t1__hammer_rebuild", v.copied...)
+			}
+
+			if err := c.ApplyDatabaseDDL(context.Background(), hammer.DDL{List: []hammer.Statement{copyTableStatement(ast.DirectionAsc)}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]int64{1, 2, 3, 4, 5}, readIDs(t, client, "t1__hammer_rebuild")); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+			if diff := cmp.Diff(v.progress, progress.String()); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClientLastCopiedKey(t *testing.T) {
+	values := []struct {
+		name     string
+		dir      ast.Direction
+		copied   []int64
+		expected spanner.Key
+	}{
+		{
+			name:     "no rows copied",
+			dir:      ast.DirectionAsc,
+			expected: nil,
+		},
+		{
+			name:     "ascending key",
+			dir:      ast.DirectionAsc,
+			copied:   []int64{1, 2, 3},
+			expected: spanner.Key{"3"},
+		},
+		{
+			name:     "descending key",
+			dir:      ast.DirectionDesc,
+			copied:   []int64{5, 4},
+			expected: spanner.Key{"4"},
+		},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			c, client, _ := newFakeClient(t, 4)
+			if len(v.copied) > 0 {
+				insertRows(t, client, "t1__hammer_rebuild", v.copied...)
+			}
+
+			key, err := c.LastCopiedKey(context.Background(), copyTableStatement(v.dir))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(v.expected, key); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cloudspannerecosystem/memefish"
 	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/cloudspannerecosystem/memefish/token"
 )
 
 type Statement interface {
@@ -14,6 +15,13 @@ type Statement interface {
 
 type DDL struct {
 	List []Statement
+	// ProtoDescriptors is a serialized FileDescriptorSet used by CREATE/ALTER PROTO BUNDLE statements.
+	ProtoDescriptors []byte
+	// Renames are hints of renamed tables and columns.
+	Renames []Rename
+
+	// locations are the source locations of the statements parsed from a schema.
+	locations map[Statement]location
 }
 
 func (d *DDL) Append(stmts ...Statement) {
@@ -22,24 +30,64 @@ func (d *DDL) Append(stmts ...Statement) {
 
 func (d *DDL) AppendDDL(ddl DDL) {
 	d.Append(ddl.List...)
+	for stmt, l := range ddl.locations {
+		if d.locations == nil {
+			d.locations = make(map[Statement]location)
+		}
+		d.locations[stmt] = l
+	}
 }
 
 func ParseDDL(uri, schema string, option *DDLOption) (DDL, error) {
-	var lines []string
-	for _, line := range strings.Split(schema, ";") {
-		trimed := strings.TrimSpace(line)
-		if trimed == "" {
+	type functionStatement struct {
+		pos  token.Pos
+		stmt Statement
+	}
+	raws, err := memefish.SplitRawStatements(uri, schema)
+	if err != nil {
+		return DDL{}, fmt.Errorf("failed to parse ddl: %s", err)
+	}
+	// the parser doesn't support user-defined functions, so they are parsed separately and
+	// blanked out keeping the positions of the other statements.
+	source := []byte(schema)
+	var functions []functionStatement
+	for _, raw := range raws {
+		stmt := parseFunctionStatement(raw.Statement)
+		if stmt == nil {
 			continue
 		}
-		lines = append(lines, line+";")
+		pos := raw.Pos + token.Pos(leadingCommentPattern.FindStringIndex(raw.Statement)[1])
+		functions = append(functions, functionStatement{pos: pos, stmt: stmt})
+		end := int(raw.End)
+		if end < len(source) && source[end] == ';' {
+			end++
+		}
+		for i := int(raw.Pos); i < end; i++ {
+			if source[i] != '\n' {
+				source[i] = ' '
+			}
+		}
 	}
 
-	ddls, err := memefish.ParseDDLs(uri, strings.Join(lines, ""))
+	file := &token.File{FilePath: uri, Buffer: string(source)}
+	ddls, err := memefish.ParseDDLs(uri, file.Buffer)
 	if err != nil {
-		return DDL{}, fmt.Errorf("%s failed to parse ddl: %s", uri, err)
+		return DDL{}, fmt.Errorf("failed to parse ddl: %s", err)
+	}
+	renames, err := parseRenameHints(file, ddls)
+	if err != nil {
+		return DDL{}, fmt.Errorf("failed to parse ddl: %s", err)
+	}
+	ddl := DDL{Renames: renames, locations: make(map[Statement]location)}
+	appendFunctions := func(pos token.Pos) {
+		for len(functions) > 0 && functions[0].pos < pos {
+			ddl.Append(functions[0].stmt)
+			ddl.locations[functions[0].stmt] = location{file: file, pos: functions[0].pos}
+			functions = functions[1:]
+		}
 	}
-	list := make([]Statement, 0, len(ddls))
 	for _, stmt := range ddls {
+		appendFunctions(stmt.Pos())
 		if _, ok := stmt.(*ast.AlterDatabase); ok && option.IgnoreAlterDatabase {
 			continue
 		}
@@ -49,34 +97,175 @@ func ParseDDL(uri, schema string, option *DDLOption) (DDL, error) {
 		if _, ok := stmt.(*ast.CreateModel); ok && option.IgnoreModels {
 			continue
 		}
-		list = append(list, stmt)
+		ddl.Append(stmt)
+		ddl.locations[stmt] = location{file: file, pos: stmt.Pos()}
 	}
-	return DDL{List: list}, nil
+	appendFunctions(token.Pos(len(source)))
+	return ddl, nil
 }
 
-type AlterColumn struct {
-	Table      string
-	Def        *ast.ColumnDef
-	SetOptions bool
+// location is a position in a schema file.
+type location struct {
+	file *token.File
+	pos  token.Pos
 }
 
-func (a AlterColumn) SQL() string {
-	str := "ALTER TABLE " + a.Table + " ALTER COLUMN " + a.Def.Name.SQL()
+func (l location) String() string {
+	line, column := l.file.ResolvePos(l.pos)
+	return fmt.Sprintf("%s:%d:%d", l.file.FilePath, line+1, column+1)
+}
 
-	if a.SetOptions {
-		v := optionsValueFromName(a.Def.Options, "allow_commit_timestamp")
-		var allowCommitTimestamp bool
-		if v != nil {
-			vt, ok := (*v).(*ast.BoolLiteral)
-			if ok {
-				allowCommitTimestamp = vt.Value
+// errorf returns an error prefixed with the source location of the statement, if the statement was parsed from a schema.
+func (d DDL) errorf(stmt Statement, format string, a ...interface{}) error {
+	if l, ok := d.locations[stmt]; ok {
+		return fmt.Errorf("%s: %s", l, fmt.Sprintf(format, a...))
+	}
+	return fmt.Errorf(format, a...)
+}
+
+// locate returns the source location of the node, if it is a part of a statement parsed from a schema.
+func locate(locations map[Statement]location, node ast.Node) (location, bool) {
+	for stmt, l := range locations {
+		n, ok := stmt.(ast.Node)
+		if !ok {
+			continue
+		}
+		found := false
+		ast.Inspect(n, func(c ast.Node) bool {
+			if c == node {
+				found = true
 			}
+			return !found
+		})
+		if found {
+			return location{file: l.file, pos: node.Pos()}, true
 		}
-		if allowCommitTimestamp {
-			return str + " SET OPTIONS (allow_commit_timestamp = true)"
-		} else {
-			return str + " SET OPTIONS (allow_commit_timestamp = null)"
+	}
+	return location{}, false
+}
+
+// validateDefinitions returns an error if an object is defined more than once, in the same or in different schema files.
+func (d DDL) validateDefinitions() error {
+	defined := make(map[string]Statement)
+	for _, stmt := range d.List {
+		if kind, name := droppedObject(stmt); kind != "" {
+			delete(defined, kind+":"+strings.ToLower(name))
+			continue
+		}
+		kind, name := definedObject(stmt)
+		if kind == "" {
+			continue
+		}
+		key := kind + ":" + strings.ToLower(name)
+		if prev, exists := defined[key]; exists {
+			object := strings.ToLower(kind)
+			if name != "" {
+				object += " " + name
+			}
+			return d.errorf(stmt, "%s is already defined at %s", object, d.locations[prev])
+		}
+		defined[key] = stmt
+	}
+	return nil
+}
+
+// definedObject returns the kind and the name of the object defined by the statement,
+// or an empty kind if the statement does not define an object.
+// CREATE OR REPLACE statements are not definitions, since they may replace an object defined elsewhere.
+func definedObject(stmt Statement) (kind, name string) {
+	switch s := stmt.(type) {
+	case *ast.CreateTable:
+		return "TABLE", identsToComparable(s.Name.Idents...)
+	case *ast.CreateView:
+		if s.OrReplace {
+			return "", ""
 		}
+		return "VIEW", identsToComparable(s.Name.Idents...)
+	case *ast.CreateIndex:
+		return "INDEX", identsToComparable(s.Name.Idents...)
+	case *ast.CreateSearchIndex:
+		return "INDEX", s.Name.Name
+	case *ast.CreateVectorIndex:
+		return "INDEX", s.Name.Name
+	case *ast.CreateChangeStream:
+		return "CHANGE STREAM", s.Name.Name
+	case *ast.CreateSequence:
+		return "SEQUENCE", identsToComparable(s.Name.Idents...)
+	case *ast.CreateModel:
+		if s.OrReplace {
+			return "", ""
+		}
+		return "MODEL", s.Name.Name
+	case *ast.CreateSchema:
+		return "SCHEMA", identsToComparable(s.Name)
+	case *ast.CreatePropertyGraph:
+		if s.OrReplace {
+			return "", ""
+		}
+		return "PROPERTY GRAPH", s.Name.Name
+	case *ast.CreateLocalityGroup:
+		return "LOCALITY GROUP", s.Name.Name
+	case *ast.CreateRole:
+		return "ROLE", s.Name.Name
+	case *ast.CreateProtoBundle:
+		return "PROTO BUNDLE", ""
+	case *CreateFunction:
+		if s.OrReplace {
+			return "", ""
+		}
+		return "FUNCTION", strings.ReplaceAll(s.Name, "`", "")
+	}
+	return "", ""
+}
+
+// droppedObject returns the kind and the name of the object dropped by the statement,
+// or an empty kind if the statement does not drop an object.
+func droppedObject(stmt Statement) (kind, name string) {
+	switch s := stmt.(type) {
+	case *ast.DropTable:
+		return "TABLE", identsToComparable(s.Name.Idents...)
+	case *ast.DropView:
+		return "VIEW", identsToComparable(s.Name.Idents...)
+	case *ast.DropIndex:
+		return "INDEX", identsToComparable(s.Name.Idents...)
+	case *ast.DropSearchIndex:
+		return "INDEX", s.Name.Name
+	case *ast.DropVectorIndex:
+		return "INDEX", s.Name.Name
+	case *ast.DropChangeStream:
+		return "CHANGE STREAM", s.Name.Name
+	case *ast.DropSequence:
+		return "SEQUENCE", identsToComparable(s.Name.Idents...)
+	case *ast.DropModel:
+		return "MODEL", s.Name.Name
+	case *ast.DropSchema:
+		return "SCHEMA", identsToComparable(s.Name)
+	case *ast.DropPropertyGraph:
+		return "PROPERTY GRAPH", s.Name.Name
+	case *ast.DropLocalityGroup:
+		return "LOCALITY GROUP", s.Name.Name
+	case *ast.DropRole:
+		return "ROLE", s.Name.Name
+	case *ast.DropProtoBundle:
+		return "PROTO BUNDLE", ""
+	case *DropFunction:
+		return "FUNCTION", strings.ReplaceAll(s.Name, "`", "")
+	}
+	return "", ""
+}
+
+type AlterColumn struct {
+	Table string
+	Def   *ast.ColumnDef
+	// Options are the options to set. If not nil, only the options are altered.
+	Options *ast.Options
+}
+
+func (a AlterColumn) SQL() string {
+	str := "ALTER TABLE " + a.Table + " ALTER COLUMN " + a.Def.Name.SQL()
+
+	if a.Options != nil {
+		return str + " SET " + a.Options.SQL()
 	}
 
 	str += " " + a.Def.Type.SQL()
@@ -96,31 +285,162 @@ func (a AlterColumn) SQL() string {
 	return str
 }
 
+// Update fills the NULL values of a column with Value.
 type Update struct {
 	Table string
 	Def   *ast.ColumnDef
+	Value string
 }
 
-func (u Update) defaultValue() string {
-	if u.Def.DefaultSemantics != nil {
-		switch t := u.Def.DefaultSemantics.(type) {
-		case *ast.ColumnDefaultExpr:
-			return t.Expr.SQL()
-		}
+// NewUpdate returns the update filling the NULL values of the column with its default value,
+// or the default value of its type. It returns an error for a proto or enum column without a default value,
+// since its zero value cannot be determined without the proto descriptor.
+func NewUpdate(table string, def *ast.ColumnDef) (Update, error) {
+	update := Update{Table: table, Def: def}
+	if d, ok := def.DefaultSemantics.(*ast.ColumnDefaultExpr); ok {
+		update.Value = d.Expr.SQL()
+		return update, nil
 	}
 
-	switch t := u.Def.Type.(type) {
+	switch t := def.Type.(type) {
 	case *ast.ArraySchemaType:
-		return "[]"
+		update.Value = "[]"
 	case *ast.ScalarSchemaType:
-		return defaultByScalarTypeName(t.Name).SQL()
+		update.Value = defaultByScalarTypeName(t.Name).SQL()
 	case *ast.SizedSchemaType:
-		return defaultByScalarTypeName(t.Name).SQL()
+		update.Value = defaultByScalarTypeName(t.Name).SQL()
 	default:
-		return "''"
+		return Update{}, fmt.Errorf("cannot fill NULL values of column %s: proto descriptor of %s is required", def.Name.SQL(), def.Type.SQL())
 	}
+	return update, nil
 }
 
 func (u Update) SQL() string {
-	return fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", u.Table, u.Def.Name.SQL(), u.defaultValue(), u.Def.Name.SQL())
+	return fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", u.Table, u.Def.Name.SQL(), u.Value, u.Def.Name.SQL())
+}
+
+// CopyTable copies the rows of a table into the shadow table used to rebuild it.
+// The client copies the rows in batches ordered by Keys and resumes after the last copied row.
+type CopyTable struct {
+	From    *ast.Path
+	To      *ast.Path
+	Columns []*ast.Ident
+	Keys    []*ast.IndexKey
+}
+
+// SQL returns a statement copying the rows at once, which is printed in place of the copy.
+// The client doesn't execute it, but copies the rows in batches with mutations instead,
+// so that a large table is copied within the mutation limit of a transaction.
+func (c CopyTable) SQL() string {
+	columns := make([]string, len(c.Columns))
+	for i, col := range c.Columns {
+		columns[i] = col.SQL()
+	}
+	return fmt.Sprintf("INSERT OR UPDATE INTO %s (%[2]s) SELECT %[2]s FROM %[3]s", c.To.SQL(), strings.Join(columns, ", "), c.From.SQL())
+}
+
+// SwapTable swaps a table with the shadow table rebuilt from it in a single RENAME TABLE statement,
+// so that the name of the table always refers to one of them: the table is renamed to Old,
+// and the shadow table to the name of the table.
+type SwapTable struct {
+	Table  *ast.Path
+	Shadow *ast.Path
+	Old    *ast.Path
+}
+
+func (s SwapTable) SQL() string {
+	return fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s", s.Table.SQL(), s.Old.SQL(), s.Shadow.SQL(), s.Table.SQL())
+}
+
+// QualifiedTableNames prints the statement with the schema-qualified table names held by single identifiers
+// as paths of identifiers, such as sch.t1 rather than `sch.t1`.
+// GRANT and CHANGE STREAM statements and the table names in queries name a table by a single identifier,
+// which holds the schema of a schema-qualified name.
+type QualifiedTableNames struct {
+	Statement
+	Names []string
+}
+
+func (q QualifiedTableNames) SQL() string {
+	sql := q.Statement.SQL()
+	for _, name := range q.Names {
+		var idents []*ast.Ident
+		for _, part := range strings.Split(name, ".") {
+			idents = append(idents, &ast.Ident{Name: part})
+		}
+		sql = strings.ReplaceAll(sql, token.QuoteSQLIdent(name), (&ast.Path{Idents: idents}).SQL())
+	}
+	return sql
+}
+
+// qualifyTableNames wraps the statement in QualifiedTableNames if it has schema-qualified table names held by single identifiers.
+func qualifyTableNames(stmt Statement) Statement {
+	node := statementNode(stmt)
+	if node == nil {
+		return stmt
+	}
+	var names []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && strings.Contains(ident.Name, ".") {
+			names = append(names, ident.Name)
+		}
+		return true
+	})
+	if len(names) == 0 {
+		return stmt
+	}
+	return QualifiedTableNames{Statement: stmt, Names: names}
+}
+
+// ConvertColumn fills a column with the values of another column converted into its type.
+type ConvertColumn struct {
+	Table *ast.Path
+	From  *ast.ColumnDef
+	To    *ast.ColumnDef
+}
+
+func (c ConvertColumn) SQL() string {
+	return fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NOT NULL", c.Table.SQL(), c.To.Name.SQL(), columnConvertExpr(c.From, c.To), c.From.Name.SQL())
+}
+
+// columnConvertExpr returns the expression converting the value of the column "from" into the type of the column "to",
+// or an empty string if the value cannot be converted.
+func columnConvertExpr(from, to *ast.ColumnDef) string {
+	if from.Type.SQL() == to.Type.SQL() {
+		return from.Name.SQL()
+	}
+	toType, ok := castType(to.Type)
+	if !ok {
+		return ""
+	}
+	_, fromIsArray := from.Type.(*ast.ArraySchemaType)
+	toArray, toIsArray := to.Type.(*ast.ArraySchemaType)
+	switch {
+	case fromIsArray && !toIsArray:
+		return ""
+	case !fromIsArray && toIsArray:
+		itemType, _ := castType(toArray.Item)
+		return "[CAST(" + from.Name.SQL() + " AS " + itemType.SQL() + ")]"
+	default:
+		return "CAST(" + from.Name.SQL() + " AS " + toType.SQL() + ")"
+	}
+}
+
+// castType returns the type used in CAST for the column type.
+func castType(t ast.SchemaType) (ast.Type, bool) {
+	switch t := t.(type) {
+	case *ast.ScalarSchemaType:
+		return &ast.SimpleType{Name: t.Name}, true
+	case *ast.SizedSchemaType:
+		return &ast.SimpleType{Name: t.Name}, true
+	case *ast.NamedType:
+		return t, true
+	case *ast.ArraySchemaType:
+		item, ok := castType(t.Item)
+		if !ok {
+			return nil, false
+		}
+		return &ast.ArrayType{Item: item}, true
+	}
+	return nil, false
 }
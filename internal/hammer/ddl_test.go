@@ -51,6 +51,26 @@ CREATE CHANGE STREAM LongerDataRetention
   Name STRING(10) NOT NULL
 ) PRIMARY KEY (UserID);
 CREATE CHANGE STREAM LongerDataRetention FOR ALL OPTIONS (retention_period = "36h");`,
+		},
+		{
+			name: "parse functions",
+			schema: `CREATE TABLE Users (
+  UserID STRING(10) NOT NULL,
+) PRIMARY KEY(UserID);
+
+-- comment
+CREATE FUNCTION Double(x INT64) RETURNS INT64 AS (x * 2);
+
+CREATE VIEW Doubled SQL SECURITY INVOKER AS SELECT Double(1) AS v;
+DROP FUNCTION IF EXISTS Triple;
+`,
+			option: &hammer.DDLOption{},
+			want: `CREATE TABLE Users (
+  UserID STRING(10) NOT NULL
+) PRIMARY KEY (UserID);
+CREATE FUNCTION Double(x INT64) RETURNS INT64 AS (x * 2);
+CREATE VIEW Doubled SQL SECURITY INVOKER AS SELECT Double(1) AS v;
+DROP FUNCTION IF EXISTS Triple;`,
 		},
 		{
 			name: "Ignore change streams",
@@ -140,6 +160,22 @@ OPTIONS (
   Name STRING(10) NOT NULL
 ) PRIMARY KEY (UserID);`,
 		},
+		{
+			name: "parse semicolons in literals and comments",
+			schema: `CREATE TABLE Users (
+  UserID STRING(10) NOT NULL, -- comment; with a semicolon
+  Name   STRING(10) NOT NULL DEFAULT ("a;b"),
+) PRIMARY KEY(UserID);
+
+CREATE FUNCTION Greet(name STRING) RETURNS STRING AS (CONCAT("hello; ", name));
+`,
+			option: &hammer.DDLOption{},
+			want: `CREATE TABLE Users (
+  UserID STRING(10) NOT NULL,
+  Name STRING(10) NOT NULL DEFAULT ("a;b")
+) PRIMARY KEY (UserID);
+CREATE FUNCTION Greet(name STRING) RETURNS STRING AS (CONCAT("hello; ", name));`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -162,6 +198,46 @@ OPTIONS (
 	}
 }
 
+func TestParseDDLErrorLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			name: "syntax error",
+			schema: `CREATE TABLE Users (
+  UserID STRING(10) NOT NULL,
+) PRIMARY KEY(UserID);
+
+CREATE INDEX UsersByName ON Users(Name;
+`,
+			want: "failed to parse ddl: syntax error: schema.sql:5:39: expected token: ), but: ;",
+		},
+		{
+			name: "invalid statement",
+			schema: `CREATE TABLE Users (
+  UserID STRING(10) NOT NULL,
+) PRIMARY KEY(UserID);
+
+ALTER TABLE Users DROP COLUMN Name;
+`,
+			want: "schema.sql:5:1: ALTER TABLE Users DROP COLUMN Name: cannot find column to drop Name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ddl, err := hammer.ParseDDL("schema.sql", tt.schema, &hammer.DDLOption{})
+			if err == nil {
+				_, err = hammer.NewDatabase(ddl)
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("got %v want error starting with %q", err, tt.want)
+			}
+		})
+	}
+}
+
 func newIdent(name string) *ast.Ident {
 	return &ast.Ident{Name: name}
 }
@@ -170,7 +246,7 @@ func TestAlterColumn_SQL(t *testing.T) {
 	values := []struct {
 		d *ast.ColumnDef
 		e string
-		s bool
+		o *ast.Options
 	}{
 		{
 			d: &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.ScalarSchemaType{Name: ast.BoolTypeName}, NotNull: true},
@@ -197,14 +273,14 @@ func TestAlterColumn_SQL(t *testing.T) {
 			e: "ALTER TABLE test_table ALTER COLUMN test_column TIMESTAMP",
 		},
 		{
-			d: &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.ScalarSchemaType{Name: ast.TimestampTypeName}, NotNull: true, Options: &ast.Options{Records: []*ast.OptionsDef{{Name: newIdent("allow_commit_timestamp"), Value: &ast.BoolLiteral{Value: true}}}}},
+			d: &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.ScalarSchemaType{Name: ast.TimestampTypeName}, NotNull: true},
 			e: "ALTER TABLE test_table ALTER COLUMN test_column SET OPTIONS (allow_commit_timestamp = true)",
-			s: true,
+			o: &ast.Options{Records: []*ast.OptionsDef{{Name: newIdent("allow_commit_timestamp"), Value: &ast.BoolLiteral{Value: true}}}},
 		},
 		{
-			d: &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.ScalarSchemaType{Name: ast.TimestampTypeName}, NotNull: true, Options: &ast.Options{Records: []*ast.OptionsDef{{Name: newIdent("allow_commit_timestamp"), Value: &ast.BoolLiteral{Value: false}}}}},
-			e: "ALTER TABLE test_table ALTER COLUMN test_column SET OPTIONS (allow_commit_timestamp = null)",
-			s: true,
+			d: &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.ScalarSchemaType{Name: ast.StringTypeName}},
+			e: "ALTER TABLE test_table ALTER COLUMN test_column SET OPTIONS (allow_commit_timestamp = null, locality_group = \"cold\")",
+			o: &ast.Options{Records: []*ast.OptionsDef{{Name: newIdent("allow_commit_timestamp"), Value: &ast.NullLiteral{}}, {Name: newIdent("locality_group"), Value: &ast.StringLiteral{Value: "cold"}}}},
 		},
 		{
 			d: &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.ScalarSchemaType{Name: ast.Int64TypeName}, DefaultSemantics: &ast.ColumnDefaultExpr{Expr: &ast.IntLiteral{Value: "1"}}},
@@ -220,7 +296,7 @@ func TestAlterColumn_SQL(t *testing.T) {
 		},
 	}
 	for _, v := range values {
-		actual := hammer.AlterColumn{Table: "test_table", Def: v.d, SetOptions: v.s}.SQL()
+		actual := hammer.AlterColumn{Table: "test_table", Def: v.d, Options: v.o}.SQL()
 
 		if actual != v.e {
 			t.Fatalf("got: %v, want: %v", actual, v.e)
@@ -280,7 +356,11 @@ func TestUpdate_SQL(t *testing.T) {
 	}
 	for i, v := range values {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			actual := hammer.Update{Table: "test_table", Def: v.d}.SQL()
+			update, err := hammer.NewUpdate("test_table", v.d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual := update.SQL()
 
 			if actual != v.s {
 				t.Fatalf("\ngot:\n%v\nwant:\n%v\n", actual, v.s)
@@ -288,3 +368,10 @@ func TestUpdate_SQL(t *testing.T) {
 		})
 	}
 }
+
+func TestNewUpdate_ProtoWithoutDefault(t *testing.T) {
+	def := &ast.ColumnDef{Name: newIdent("test_column"), Type: &ast.NamedType{Path: []*ast.Ident{newIdent("examples"), newIdent("Item")}}}
+	if _, err := hammer.NewUpdate("test_table", def); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -11,7 +11,19 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
-func Diff(ddl1, ddl2 DDL) (DDL, error) {
+type DiffOption struct {
+	// RebuildTable rebuilds tables whose primary key or interleave parent changed into a shadow table
+	// and copies the rows, instead of dropping and recreating them.
+	RebuildTable bool
+	// ConvertColumnType converts the values of columns whose type cannot be altered in place,
+	// instead of dropping and adding the columns.
+	ConvertColumnType bool
+}
+
+func Diff(ddl1, ddl2 DDL, option *DiffOption) (DDL, error) {
+	if option == nil {
+		option = &DiffOption{}
+	}
 	database1, err := NewDatabase(ddl1)
 	if err != nil {
 		return DDL{}, err
@@ -24,10 +36,16 @@ func Diff(ddl1, ddl2 DDL) (DDL, error) {
 	generator := &Generator{
 		from:                             database1,
 		to:                               database2,
+		option:                           option,
 		willCreateOrAlterChangeStreamIDs: map[string]*ChangeStream{},
 		alteredChangeStreamStates:        map[string]*ChangeStream{},
 	}
-	return generator.GenerateDDL(), nil
+	if option.RebuildTable {
+		if err := generator.validateRebuildTables(); err != nil {
+			return DDL{}, err
+		}
+	}
+	return generator.GenerateDDL()
 }
 
 func NewDatabase(ddl DDL) (*Database, error) {
@@ -35,41 +53,73 @@ func NewDatabase(ddl DDL) (*Database, error) {
 		tables               []*Table
 		changeStreams        []*ChangeStream
 		views                []*View
+		models               []*Model
+		sequences            []*Sequence
+		schemas              []*Schema
+		propertyGraphs       []*PropertyGraph
+		localityGroups       []*LocalityGroup
+		functions            []*CreateFunction
+		protoBundle          *ProtoBundle
 		roles                []*Role
 		grants               []*Grant
 		alterDatabaseOptions *ast.AlterDatabase
 		options              *ast.Options
 	)
 
+	// tables are keyed case-insensitively, as findTableByName compares their names.
 	m := make(map[string]*Table)
+	tableKey := func(idents ...*ast.Ident) string { return strings.ToLower(identsToComparable(idents...)) }
+	// the statements are copied, since folding alterations and renames changes them in place.
+	// The copies are located at the same positions as the statements.
+	stmts := make([]Statement, len(ddl.List))
+	locations := make(map[Statement]location, len(ddl.List))
+	for i, stmt := range ddl.List {
+		stmts[i] = cloneStatement(stmt)
+		if loc, ok := ddl.locations[stmt]; ok {
+			locations[stmts[i]] = loc
+		}
+	}
+	ddl.List = stmts
+	ddl.locations = locations
 	for _, istmt := range ddl.List {
 		switch stmt := istmt.(type) {
 		case *ast.CreateTable:
 			t := &Table{CreateTable: stmt}
 			tables = append(tables, t)
-			m[identsToComparable(stmt.Name.Idents...)] = t
+			m[tableKey(stmt.Name.Idents...)] = t
 		case *ast.CreateIndex:
-			if t, ok := m[identsToComparable(stmt.TableName.Idents...)]; ok {
+			if t, ok := m[tableKey(stmt.TableName.Idents...)]; ok {
 				t.indexes = append(t.indexes, stmt)
 			} else {
-				return nil, fmt.Errorf("cannot find ddl of table to apply index %s", stmt.Name.SQL())
+				return nil, ddl.errorf(istmt, "cannot find ddl of table to apply index %s", stmt.Name.SQL())
 			}
 		case *ast.CreateSearchIndex:
-			if t, ok := m[identsToComparable(stmt.TableName)]; ok {
+			if t, ok := m[tableKey(stmt.TableName)]; ok {
 				t.searchIndexes = append(t.searchIndexes, stmt)
 			} else {
-				return nil, fmt.Errorf("cannot find ddl of table to apply search index %s", stmt.Name.SQL())
+				return nil, ddl.errorf(istmt, "cannot find ddl of table to apply search index %s", stmt.Name.SQL())
+			}
+		case *ast.CreateVectorIndex:
+			if t, ok := m[tableKey(stmt.TableName)]; ok {
+				t.vectorIndexes = append(t.vectorIndexes, stmt)
+			} else {
+				return nil, ddl.errorf(istmt, "cannot find ddl of table to apply vector index %s", stmt.Name.SQL())
 			}
 		case *ast.AlterTable:
-			t, ok := m[identsToComparable(stmt.Name.Idents...)]
+			key := tableKey(stmt.Name.Idents...)
+			t, ok := m[key]
 			if !ok {
-				return nil, fmt.Errorf("cannot find ddl of table to apply index %s", stmt.Name.SQL())
+				return nil, ddl.errorf(istmt, "cannot find ddl of table to alter %s", stmt.Name.SQL())
 			}
-			switch alteration := stmt.TableAlteration.(type) {
-			case *ast.AddTableConstraint:
-				t.TableConstraints = append(t.TableConstraints, alteration.TableConstraint)
-			default:
-				return nil, fmt.Errorf("unsupported table alteration: %v", stmt)
+			oldName := identsToComparable(t.Name.Idents...)
+			if err := t.alter(stmt.TableAlteration); err != nil {
+				return nil, ddl.errorf(istmt, "%s: %s", stmt.SQL(), err)
+			}
+			if _, ok := stmt.TableAlteration.(*ast.RenameTo); ok {
+				delete(m, key)
+				m[tableKey(t.Name.Idents...)] = t
+				// the objects defined so far are rewritten the same as in the database being diffed.
+				(&Database{tables: tables, changeStreams: changeStreams, views: views, grants: grants}).renameTableReferences(oldName, t)
 			}
 		case *ast.AlterDatabase:
 			alterDatabaseOptions = stmt
@@ -78,7 +128,7 @@ func NewDatabase(ddl DDL) (*Database, error) {
 			switch forType := stmt.For.(type) {
 			case *ast.ChangeStreamForTables:
 				for _, table := range forType.Tables {
-					if t, ok := m[identsToComparable(table.TableName)]; ok {
+					if t, ok := m[tableKey(table.TableName)]; ok {
 						t.changeStreams = append(t.changeStreams, &ChangeStream{CreateChangeStream: stmt})
 					}
 				}
@@ -88,35 +138,153 @@ func NewDatabase(ddl DDL) (*Database, error) {
 		case *ast.CreateView:
 			v := &View{CreateView: stmt}
 			views = append(views, v)
+		case *ast.CreateModel:
+			models = append(models, &Model{CreateModel: stmt})
+		case *ast.CreateSequence:
+			sequences = append(sequences, &Sequence{CreateSequence: stmt})
+		case *ast.CreateSchema:
+			schemas = append(schemas, &Schema{CreateSchema: stmt})
+		case *ast.CreatePropertyGraph:
+			propertyGraphs = append(propertyGraphs, &PropertyGraph{CreatePropertyGraph: stmt})
+		case *ast.CreateLocalityGroup:
+			localityGroups = append(localityGroups, &LocalityGroup{CreateLocalityGroup: stmt})
+		case *ast.AlterLocalityGroup:
+			var found bool
+			for _, lg := range localityGroups {
+				if strings.EqualFold(lg.Name.Name, stmt.Name.Name) {
+					lg.Options = mergeOptions(lg.Options, stmt.Options)
+					found = true
+					break
+				}
+			}
+			if !found {
+				if !strings.EqualFold(stmt.Name.Name, defaultLocalityGroup) {
+					return nil, ddl.errorf(istmt, "cannot find ddl of locality group to alter %s", stmt.Name.SQL())
+				}
+				// the default locality group exists without CREATE LOCALITY GROUP.
+				localityGroups = append(localityGroups, &LocalityGroup{CreateLocalityGroup: &ast.CreateLocalityGroup{Name: stmt.Name, Options: stmt.Options}})
+			}
+		case *ast.DropLocalityGroup:
+			var found bool
+			for i, lg := range localityGroups {
+				if strings.EqualFold(lg.Name.Name, stmt.Name.Name) {
+					localityGroups = append(localityGroups[:i], localityGroups[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, ddl.errorf(istmt, "cannot find ddl of locality group to drop %s", stmt.Name.SQL())
+			}
+		case *ast.CreateProtoBundle:
+			protoBundle = &ProtoBundle{}
+			protoBundle.insert(stmt.Types.Types...)
+		case *ast.AlterProtoBundle:
+			if protoBundle == nil {
+				return nil, ddl.errorf(istmt, "cannot find ddl of proto bundle to alter")
+			}
+			if stmt.Insert != nil {
+				protoBundle.insert(stmt.Insert.Types.Types...)
+			}
+			if stmt.Delete != nil {
+				if err := protoBundle.delete(stmt.Delete.Types.Types...); err != nil {
+					return nil, ddl.errorf(istmt, "%s", err)
+				}
+			}
+		case *ast.DropProtoBundle:
+			protoBundle = nil
+		case *ast.DropSchema:
+			var found bool
+			for i, s := range schemas {
+				if strings.EqualFold(identsToComparable(s.Name), identsToComparable(stmt.Name)) {
+					schemas = append(schemas[:i], schemas[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, ddl.errorf(istmt, "cannot find ddl of schema to drop %s", stmt.Name.SQL())
+			}
+		case *CreateFunction:
+			var found bool
+			for i, f := range functions {
+				if functionNameEqual(f.Name, stmt.Name) {
+					functions[i] = stmt
+					found = true
+					break
+				}
+			}
+			if !found {
+				functions = append(functions, stmt)
+			}
+		case *DropFunction:
+			var found bool
+			for i, f := range functions {
+				if functionNameEqual(f.Name, stmt.Name) {
+					functions = append(functions[:i], functions[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found && !stmt.IfExists {
+				return nil, ddl.errorf(istmt, "cannot find ddl of function to drop %s", stmt.Name)
+			}
 		case *ast.CreateRole:
 			roles = append(roles, &Role{CreateRole: stmt})
 		case *ast.Grant:
 			grants = append(grants, &Grant{Grant: stmt})
 		default:
-			return nil, fmt.Errorf("unexpected ddl statement: %v", stmt.SQL())
+			return nil, ddl.errorf(istmt, "unexpected ddl statement: %v", stmt.SQL())
 		}
 	}
 	for _, t := range tables {
 		if i := t.Cluster; i != nil {
-			if p, ok := m[identsToComparable(i.TableName.Idents...)]; ok {
+			if p, ok := m[tableKey(i.TableName.Idents...)]; ok {
 				p.children = append(p.children, t)
 			} else {
-				return nil, fmt.Errorf("parent ddl %s not found", i.TableName.SQL())
+				return nil, ddl.errorf(t.CreateTable, "parent ddl %s not found", i.TableName.SQL())
 			}
 		}
 	}
 
-	return &Database{tables: tables, changeStreams: changeStreams, views: views, roles: roles, grants: grants, alterDatabaseOptions: alterDatabaseOptions, options: options}, nil
+	roleGraph, err := newRoleGraph(ddl, roles, grants)
+	if err != nil {
+		return nil, err
+	}
+
+	var protoDescriptors map[string]*protoDescriptor
+	if len(ddl.ProtoDescriptors) > 0 {
+		descriptors, err := parseProtoDescriptors(ddl.ProtoDescriptors)
+		if err != nil {
+			return nil, err
+		}
+		protoDescriptors = descriptors
+	}
+
+	return &Database{tables: tables, changeStreams: changeStreams, views: views, models: models, sequences: sequences, schemas: schemas, propertyGraphs: propertyGraphs, localityGroups: localityGroups, functions: functions, protoBundle: protoBundle, protoDescriptors: protoDescriptors, rawProtoDescriptors: ddl.ProtoDescriptors, renames: ddl.Renames, locations: ddl.locations, roles: roles, grants: grants, roleGraph: roleGraph, alterDatabaseOptions: alterDatabaseOptions, options: options}, nil
 }
 
 type Database struct {
 	tables               []*Table
 	changeStreams        []*ChangeStream
 	views                []*View
+	models               []*Model
+	sequences            []*Sequence
+	schemas              []*Schema
+	propertyGraphs       []*PropertyGraph
+	localityGroups       []*LocalityGroup
+	functions            []*CreateFunction
+	protoBundle          *ProtoBundle
+	protoDescriptors     map[string]*protoDescriptor
+	rawProtoDescriptors  []byte
+	renames              []Rename
 	roles                []*Role
 	grants               []*Grant
+	roleGraph            *roleGraph
 	alterDatabaseOptions *ast.AlterDatabase
 	options              *ast.Options
+	// locations are the source locations of the statements the database is built from.
+	locations map[Statement]location
 }
 
 func (d *Database) grantsOnTable(table *Table) []*Grant {
@@ -208,18 +376,322 @@ type Table struct {
 
 	indexes       []*ast.CreateIndex
 	searchIndexes []*ast.CreateSearchIndex
+	vectorIndexes []*ast.CreateVectorIndex
 	children      []*Table
 	changeStreams []*ChangeStream
 }
 
+// alter folds the table alteration into the table definition.
+func (t *Table) alter(alteration ast.TableAlteration) error {
+	switch a := alteration.(type) {
+	case *ast.AddColumn:
+		if _, exists := t.findColumn(a.Column.Name.Name); exists {
+			if a.IfNotExists {
+				return nil
+			}
+			return fmt.Errorf("column %s already exists", a.Column.Name.SQL())
+		}
+		t.Columns = append(t.Columns, a.Column)
+	case *ast.DropColumn:
+		for i, col := range t.Columns {
+			if strings.EqualFold(col.Name.Name, a.Name.Name) {
+				t.Columns = append(t.Columns[:i], t.Columns[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot find column to drop %s", a.Name.SQL())
+	case *ast.AlterColumn:
+		col, exists := t.findColumn(a.Name.Name)
+		if !exists {
+			return fmt.Errorf("cannot find column to alter %s", a.Name.SQL())
+		}
+		return alterColumn(col, a.Alteration)
+	case *ast.AddTableConstraint:
+		t.TableConstraints = append(t.TableConstraints, a.TableConstraint)
+	case *ast.DropConstraint:
+		for i, tc := range t.TableConstraints {
+			if tc.Name != nil && strings.EqualFold(tc.Name.Name, a.Name.Name) {
+				t.TableConstraints = append(t.TableConstraints[:i], t.TableConstraints[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot find constraint to drop %s", a.Name.SQL())
+	case *ast.AddRowDeletionPolicy:
+		if t.RowDeletionPolicy != nil {
+			return fmt.Errorf("row deletion policy already exists")
+		}
+		t.RowDeletionPolicy = &ast.CreateRowDeletionPolicy{RowDeletionPolicy: a.RowDeletionPolicy}
+	case *ast.ReplaceRowDeletionPolicy:
+		if t.RowDeletionPolicy == nil {
+			return fmt.Errorf("cannot find row deletion policy to replace")
+		}
+		t.RowDeletionPolicy = &ast.CreateRowDeletionPolicy{RowDeletionPolicy: a.RowDeletionPolicy}
+	case *ast.DropRowDeletionPolicy:
+		if t.RowDeletionPolicy == nil {
+			return fmt.Errorf("cannot find row deletion policy to drop")
+		}
+		t.RowDeletionPolicy = nil
+	case *ast.SetOnDelete:
+		if t.Cluster == nil {
+			return fmt.Errorf("cannot set on delete action of non-interleaved table")
+		}
+		t.Cluster.OnDelete = a.OnDelete
+	case *ast.SetInterleaveIn:
+		if t.Cluster == nil {
+			return fmt.Errorf("cannot set interleave of non-interleaved table")
+		}
+		t.Cluster = &ast.Cluster{TableName: a.TableName, Enforced: a.Enforced, OnDelete: a.OnDelete}
+	case *ast.AlterTableSetOptions:
+		t.Options = mergeOptions(t.Options, a.Options)
+	case *ast.AddSynonym:
+		t.Synonyms = append(t.Synonyms, &ast.Synonym{Name: a.Name})
+	case *ast.DropSynonym:
+		for i, s := range t.Synonyms {
+			if strings.EqualFold(s.Name.Name, a.Name.Name) {
+				t.Synonyms = append(t.Synonyms[:i], t.Synonyms[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot find synonym to drop %s", a.Name.SQL())
+	case *ast.RenameTo:
+		idents := append([]*ast.Ident{}, t.Name.Idents[:len(t.Name.Idents)-1]...)
+		name := &ast.Path{Idents: append(idents, a.Name)}
+		if a.AddSynonym != nil {
+			t.Synonyms = append(t.Synonyms, &ast.Synonym{Name: a.AddSynonym.Name})
+		}
+		for _, i := range t.indexes {
+			i.TableName = name
+		}
+		for _, i := range t.searchIndexes {
+			i.TableName = a.Name
+		}
+		for _, i := range t.vectorIndexes {
+			i.TableName = a.Name
+		}
+		t.Name = name
+	default:
+		return fmt.Errorf("unsupported table alteration")
+	}
+	return nil
+}
+
+func (t *Table) findColumn(name string) (*ast.ColumnDef, bool) {
+	for _, col := range t.Columns {
+		if strings.EqualFold(col.Name.Name, name) {
+			return col, true
+		}
+	}
+	return nil, false
+}
+
+// alterColumn folds the column alteration into the column definition.
+func alterColumn(col *ast.ColumnDef, alteration ast.ColumnAlteration) error {
+	switch a := alteration.(type) {
+	case *ast.AlterColumnType:
+		col.Type = a.Type
+		col.NotNull = a.NotNull
+		if a.DefaultExpr != nil {
+			col.DefaultSemantics = a.DefaultExpr
+		} else if _, ok := col.DefaultSemantics.(*ast.ColumnDefaultExpr); ok {
+			col.DefaultSemantics = nil
+		}
+	case *ast.AlterColumnSetOptions:
+		col.Options = mergeOptions(col.Options, a.Options)
+	case *ast.AlterColumnSetDefault:
+		col.DefaultSemantics = a.DefaultExpr
+	case *ast.AlterColumnDropDefault:
+		if _, ok := col.DefaultSemantics.(*ast.ColumnDefaultExpr); !ok {
+			return fmt.Errorf("column %s has no default value", col.Name.SQL())
+		}
+		col.DefaultSemantics = nil
+	case *ast.AlterColumnAlterIdentity:
+		identity, ok := col.DefaultSemantics.(*ast.IdentityColumn)
+		if !ok {
+			return fmt.Errorf("column %s is not an identity column", col.Name.SQL())
+		}
+		identity.Params = alterIdentityParams(identity.Params, a.Alteration)
+	default:
+		return fmt.Errorf("unsupported column alteration")
+	}
+	return nil
+}
+
+func alterIdentityParams(params []ast.SequenceParam, alteration ast.IdentityAlteration) []ast.SequenceParam {
+	var result []ast.SequenceParam
+	for _, p := range params {
+		switch p.(type) {
+		case *ast.StartCounterWith:
+			if _, ok := alteration.(*ast.RestartCounterWith); ok {
+				continue
+			}
+		case *ast.SkipRange:
+			if _, ok := alteration.(*ast.RestartCounterWith); !ok {
+				continue
+			}
+		}
+		result = append(result, p)
+	}
+	switch a := alteration.(type) {
+	case *ast.RestartCounterWith:
+		result = append(result, &ast.StartCounterWith{Counter: a.Counter})
+	case *ast.SetSkipRange:
+		result = append(result, a.SkipRange)
+	}
+	return result
+}
+
+// mergeOptions returns the options with the records of update applied. Options set to null are removed.
+func mergeOptions(base, update *ast.Options) *ast.Options {
+	var records []*ast.OptionsDef
+	if base != nil {
+		records = append(records, base.Records...)
+	}
+	for _, u := range update.Records {
+		_, isNull := u.Value.(*ast.NullLiteral)
+		replaced := false
+		for i, r := range records {
+			if strings.EqualFold(r.Name.Name, u.Name.Name) {
+				if isNull {
+					records = append(records[:i], records[i+1:]...)
+				} else {
+					records[i] = u
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced && !isNull {
+			records = append(records, u)
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return &ast.Options{Records: records}
+}
+
 type View struct {
 	*ast.CreateView
 }
 
+type Model struct {
+	*ast.CreateModel
+}
+
+type Schema struct {
+	*ast.CreateSchema
+}
+
+type Sequence struct {
+	*ast.CreateSequence
+}
+
+// normalizedOptions returns the options of the sequence, including those given as clauses such as
+// BIT_REVERSED_POSITIVE, SKIP RANGE and START COUNTER WITH.
+func (s *Sequence) normalizedOptions() *ast.Options {
+	options := &ast.Options{}
+	for _, param := range s.Params {
+		switch p := param.(type) {
+		case *ast.BitReversedPositive:
+			options.Records = append(options.Records, &ast.OptionsDef{
+				Name:  &ast.Ident{Name: "sequence_kind"},
+				Value: &ast.StringLiteral{Value: "bit_reversed_positive"},
+			})
+		case *ast.SkipRange:
+			options.Records = append(options.Records, &ast.OptionsDef{
+				Name:  &ast.Ident{Name: "skip_range_min"},
+				Value: p.Min,
+			}, &ast.OptionsDef{
+				Name:  &ast.Ident{Name: "skip_range_max"},
+				Value: p.Max,
+			})
+		case *ast.StartCounterWith:
+			options.Records = append(options.Records, &ast.OptionsDef{
+				Name:  &ast.Ident{Name: "start_with_counter"},
+				Value: p.Counter,
+			})
+		}
+	}
+	if s.Options != nil {
+		options.Records = append(options.Records, s.Options.Records...)
+	}
+	return options
+}
+
+type PropertyGraph struct {
+	*ast.CreatePropertyGraph
+}
+
+// referencesTable returns true if the table is used as a node table or an edge table of the property graph.
+func (pg *PropertyGraph) referencesTable(name string) bool {
+	var elements []*ast.PropertyGraphElement
+	if t := pg.Content.NodeTables; t != nil {
+		elements = append(elements, t.Tables.Elements...)
+	}
+	if t := pg.Content.EdgeTables; t != nil {
+		elements = append(elements, t.Tables.Elements...)
+	}
+	for _, e := range elements {
+		if strings.EqualFold(identsToComparable(e.Name), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// references returns the names of the tables and views the view reads from.
+func (v *View) references() []string {
+	var names []string
+	ast.Inspect(v.Query, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.TableName:
+			names = append(names, identsToComparable(t.Table))
+		case *ast.PathTableExpr:
+			names = append(names, identsToComparable(t.Path.Idents...))
+		}
+		return true
+	})
+	return names
+}
+
+// sortViewsByDependency sorts the views so that each view comes after the views it reads from.
+// Views without dependencies between them keep their original order.
+func sortViewsByDependency(views []*View) []*View {
+	sorted := make([]*View, 0, len(views))
+	visited := make(map[*View]bool)
+
+	var visit func(v *View)
+	visit = func(v *View) {
+		if visited[v] {
+			return
+		}
+		visited[v] = true
+		for _, ref := range v.references() {
+			for _, dep := range views {
+				if strings.EqualFold(identsToComparable(dep.Name.Idents...), ref) {
+					visit(dep)
+				}
+			}
+		}
+		sorted = append(sorted, v)
+	}
+	for _, v := range views {
+		visit(v)
+	}
+	return sorted
+}
+
 type Role struct {
 	*ast.CreateRole
 }
 
+// defaultLocalityGroup is the name of the locality group which always exists and can only be altered.
+const defaultLocalityGroup = "default"
+
+type LocalityGroup struct {
+	*ast.CreateLocalityGroup
+}
+
 type Grant struct {
 	*ast.Grant
 }
@@ -248,30 +720,136 @@ func (cs *ChangeStream) Type() ChangeStreamType {
 	}
 }
 
+const (
+	// rebuildTableSuffix is appended to the name of the shadow table used to rebuild a table.
+	rebuildTableSuffix = "__hammer_rebuild"
+	// replacedTableSuffix is appended to the name of a rebuilt table when it is swapped with the shadow table.
+	replacedTableSuffix = "__hammer_replaced"
+	// convertColumnSuffix is appended to the name of the temporary column used to convert a column type.
+	convertColumnSuffix = "__hammer_convert"
+)
+
 type Generator struct {
-	from *Database
-	to   *Database
+	from   *Database
+	to     *Database
+	option *DiffOption
 
 	dropedTable                      []string
+	rebuiltTable                     []string
+	renamedTable                     []string
 	dropedIndex                      []string
 	dropedChangeStream               []string
+	droppedView                      []string
+	droppedPropertyGraph             []string
 	droppedConstraints               []*ast.TableConstraint
 	droppedGrant                     []*Grant
 	willCreateOrAlterChangeStreamIDs map[string]*ChangeStream
 	alteredChangeStreamStates        map[string]*ChangeStream
+
+	// err is the first error found while generating the DDL, such as a statement that cannot be applied.
+	err error
+}
+
+// fail records the error, so that GenerateDDL returns the first error found.
+func (g *Generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// errorf returns an error prefixed with the source location of the node in the target schema, or else in the source schema,
+// if it is found in them.
+func (g *Generator) errorf(node ast.Node, format string, a ...interface{}) error {
+	if node != nil {
+		for _, d := range []*Database{g.to, g.from} {
+			if l, ok := locate(d.locations, node); ok {
+				return fmt.Errorf("%s: %s", l, fmt.Sprintf(format, a...))
+			}
+		}
+	}
+	return fmt.Errorf(format, a...)
 }
 
-func (g *Generator) GenerateDDL() DDL {
+func (g *Generator) GenerateDDL() (DDL, error) {
 	ddl := DDL{}
 
 	// for alter database
 	ddl.AppendDDL(g.generateDDLForAlterDatabaseOptions())
 
+	// create schemas before their contents
+	for _, toSchema := range g.to.schemas {
+		if _, exists := g.findSchemaByName(g.from.schemas, identsToComparable(toSchema.Name)); !exists {
+			ddl.Append(toSchema)
+		}
+	}
+
+	// rename tables and columns first, so that the rest of the diff compares them by their new names
+	renames := g.generateDDLForRenames()
+
+	// drop property graphs before their node and edge tables are renamed, altered or dropped
+	for _, fromGraph := range g.from.propertyGraphs {
+		if _, exists := g.findPropertyGraphByName(g.to.propertyGraphs, identsToComparable(fromGraph.Name)); !exists {
+			ddl.AppendDDL(g.generateDDLForDropPropertyGraph(fromGraph))
+			continue
+		}
+		for _, name := range g.renamedTable {
+			if fromGraph.referencesTable(name) {
+				ddl.AppendDDL(g.generateDDLForDropPropertyGraph(fromGraph))
+				break
+			}
+		}
+	}
+
+	// drop views before the tables and views they read from are renamed or dropped
+	ddl.AppendDDL(g.generateDDLForDropViews())
+	ddl.AppendDDL(renames)
+
+	// create or alter proto bundle before the columns using its types
+	ddl.AppendDDL(g.generateDDLForCreateOrAlterProtoBundle())
+
+	// create or alter locality groups before the tables and columns placed in them
+	for _, toGroup := range g.to.localityGroups {
+		fromGroup, exists := g.findLocalityGroupByName(g.from.localityGroups, toGroup.Name.Name)
+		if !exists && !strings.EqualFold(toGroup.Name.Name, defaultLocalityGroup) {
+			ddl.Append(toGroup)
+			continue
+		}
+		var fromOptions *ast.Options
+		if exists {
+			fromOptions = fromGroup.Options
+		}
+		ddl.AppendDDL(g.generateDDLForAlterLocalityGroup(toGroup.Name, fromOptions, toGroup.Options))
+	}
+
+	// for sequences
+	for _, toSequence := range g.to.sequences {
+		fromSequence, exists := g.findSequenceByName(g.from.sequences, identsToComparable(toSequence.Name.Idents...))
+		if !exists {
+			ddl.Append(toSequence)
+			continue
+		}
+		ddl.AppendDDL(g.generateDDLForAlterSequence(fromSequence, toSequence))
+	}
+
+	// create new functions and replace changed ones before the generated columns and views using them
+	for _, toFunction := range g.to.functions {
+		fromFunction, exists := g.findFunctionByName(g.from.functions, toFunction.Name)
+		if exists && g.functionEqual(fromFunction, toFunction) {
+			continue
+		}
+		ddl.Append(&CreateFunction{OrReplace: exists, Name: toFunction.Name, Definition: toFunction.Definition})
+	}
+
 	// for alter table
 	for _, toTable := range g.to.tables {
 		fromTable, exists := g.findTableByName(g.from.tables, identsToComparable(toTable.Name.Idents...))
 
 		if !exists {
+			// the table was dropped by an interrupted rebuild before the shadow table was renamed.
+			if _, rebuilding := g.findTableByName(g.from.tables, identsToComparable(rebuildTableName(toTable.Name).Idents...)); rebuilding && g.option.RebuildTable {
+				ddl.AppendDDL(g.generateDDLForSwapRebuiltTable(toTable))
+				continue
+			}
 			ddl.AppendDDL(g.generateDDLForCreateTableAndIndex(toTable))
 			continue
 		}
@@ -281,20 +859,20 @@ func (g *Generator) GenerateDDL() DDL {
 			continue
 		}
 
-		if !g.interleaveEqual(fromTable, toTable) {
-			ddl.AppendDDL(g.generateDDLForDropConstraintIndexAndTable(fromTable))
-			ddl.AppendDDL(g.generateDDLForCreateTableAndIndex(toTable))
-			continue
-		}
-
-		if !g.primaryKeyEqual(fromTable, toTable) {
+		if g.requireRecreateTable(fromTable, toTable) {
+			if g.option.RebuildTable {
+				ddl.AppendDDL(g.generateDDLForRebuildTable(fromTable, toTable))
+				continue
+			}
 			ddl.AppendDDL(g.generateDDLForDropConstraintIndexAndTable(fromTable))
 			ddl.AppendDDL(g.generateDDLForCreateTableAndIndex(toTable))
 			continue
 		}
 
+		ddl.AppendDDL(g.generateDDLForInterleave(fromTable, toTable))
 		ddl.AppendDDL(g.generateDDLForDropIndex(fromTable, toTable))
 		ddl.AppendDDL(g.generateDDLForColumns(fromTable, toTable))
+		ddl.AppendDDL(g.generateDDLForTableOptions(fromTable, toTable))
 		ddl.AppendDDL(g.generateDDLForCreateIndex(fromTable, toTable))
 		ddl.AppendDDL(g.generateDDLForAlterIndex(fromTable, toTable))
 		ddl.AppendDDL(g.generateDDLForConstraints(fromTable, toTable))
@@ -323,10 +901,37 @@ func (g *Generator) GenerateDDL() DDL {
 	}
 	// drop tables
 	for _, fromTable := range g.from.tables {
+		if g.isRebuiltTable(identsToComparable(fromTable.Name.Idents...)) {
+			continue
+		}
 		if _, exists := g.findTableByName(g.to.tables, identsToComparable(fromTable.Name.Idents...)); !exists {
 			ddl.AppendDDL(g.generateDDLForDropConstraintIndexAndTable(fromTable))
 		}
 	}
+	// drop functions after the generated columns and views using them are dropped
+	for _, fromFunction := range g.from.functions {
+		if _, exists := g.findFunctionByName(g.to.functions, fromFunction.Name); !exists {
+			ddl.AppendDDL(g.generateDDLForDropFunction(fromFunction))
+		}
+	}
+	// drop locality groups after the tables and columns placed in them are dropped or moved
+	for _, fromGroup := range g.from.localityGroups {
+		if _, exists := g.findLocalityGroupByName(g.to.localityGroups, fromGroup.Name.Name); exists {
+			continue
+		}
+		if strings.EqualFold(fromGroup.Name.Name, defaultLocalityGroup) {
+			ddl.AppendDDL(g.generateDDLForAlterLocalityGroup(fromGroup.Name, fromGroup.Options, nil))
+			continue
+		}
+		ddl.Append(&ast.DropLocalityGroup{Name: fromGroup.Name})
+	}
+	// drop sequences
+	// Column defaults using GET_NEXT_SEQUENCE_VALUE have already been altered or dropped above.
+	for _, fromSequence := range g.from.sequences {
+		if _, exists := g.findSequenceByName(g.to.sequences, identsToComparable(fromSequence.Name.Idents...)); !exists {
+			ddl.Append(&ast.DropSequence{Name: fromSequence.Name})
+		}
+	}
 	// drop change streams
 	for _, fromChangeStream := range g.from.changeStreams {
 		if g.isDropedChangeStream(identsToComparable(fromChangeStream.Name)) {
@@ -352,17 +957,41 @@ func (g *Generator) GenerateDDL() DDL {
 		}
 	}
 	// for views
-	for _, toView := range g.to.views {
-		_, exists := g.findViewByName(g.from.views, identsToComparable(toView.Name.Idents...))
-		if !exists {
+	for _, toView := range sortViewsByDependency(g.to.views) {
+		fromView, exists := g.findViewByName(g.from.views, identsToComparable(toView.Name.Idents...))
+		if !exists || g.isDroppedView(identsToComparable(toView.Name.Idents...)) {
 			ddl.Append(toView)
 			continue
 		}
-		ddl.AppendDDL(g.generateDDLForReplaceView(toView))
+		if !g.viewEqual(fromView, toView) {
+			ddl.AppendDDL(g.generateDDLForReplaceView(toView))
+		}
+	}
+
+	// for property graphs
+	for _, toGraph := range g.to.propertyGraphs {
+		fromGraph, exists := g.findPropertyGraphByName(g.from.propertyGraphs, identsToComparable(toGraph.Name))
+		if !exists || g.isDroppedPropertyGraph(identsToComparable(toGraph.Name)) {
+			ddl.Append(toGraph)
+			continue
+		}
+		if !g.propertyGraphEqual(fromGraph, toGraph) {
+			ddl.Append(&ast.CreatePropertyGraph{OrReplace: true, Name: toGraph.Name, Content: toGraph.Content})
+		}
+	}
+
+	// for models
+	for _, toModel := range g.to.models {
+		fromModel, exists := g.findModelByName(g.from.models, identsToComparable(toModel.Name))
+		if !exists {
+			ddl.Append(toModel)
+			continue
+		}
+		ddl.AppendDDL(g.generateDDLForAlterModel(fromModel, toModel))
 	}
-	for _, fromView := range g.from.views {
-		if _, exists := g.findViewByName(g.to.views, identsToComparable(fromView.Name.Idents...)); !exists {
-			ddl.AppendDDL(g.generateDDLForDropView(fromView))
+	for _, fromModel := range g.from.models {
+		if _, exists := g.findModelByName(g.to.models, identsToComparable(fromModel.Name)); !exists {
+			ddl.Append(&ast.DropModel{Name: fromModel.Name})
 		}
 	}
 
@@ -374,33 +1003,96 @@ func (g *Generator) GenerateDDL() DDL {
 			continue
 		}
 	}
+	var droppedRoles []*Role
 	for _, fromRole := range g.from.roles {
 		roleName := identsToComparable(fromRole.Name)
 		if _, exists := g.findRoleByName(g.to.roles, roleName); !exists {
-			ddl.AppendDDL(g.generateDDLForDropRole(fromRole))
+			droppedRoles = append(droppedRoles, fromRole)
 		}
 	}
+	for _, role := range g.from.roleGraph.childFirst(droppedRoles) {
+		ddl.AppendDDL(g.generateDDLForDropRole(role))
+	}
 
 	// for grants
-	for _, fromGrant := range g.from.grants {
-		if _, exists := g.findGrant(g.to.grants, fromGrant); !exists {
-			if g.isDroppedGrant(fromGrant) {
-				continue
-			}
-			ddl.AppendDDL(g.generateDDLForRevokeAll(fromGrant))
+	ddl.AppendDDL(g.generateDDLForGrants())
+
+	// delete proto bundle types after the columns using them
+	ddl.AppendDDL(g.generateDDLForDeleteOrDropProtoBundle())
+
+	// drop schemas after their contents
+	for _, fromSchema := range g.from.schemas {
+		if _, exists := g.findSchemaByName(g.to.schemas, identsToComparable(fromSchema.Name)); !exists {
+			ddl.Append(&ast.DropSchema{Name: fromSchema.Name})
 		}
 	}
-	for _, toGrant := range g.to.grants {
-		if fromGrant, exists := g.findGrant(g.from.grants, toGrant); exists {
-			if g.isDroppedGrant(fromGrant) {
-				ddl.Append(toGrant)
-			}
-		} else {
-			ddl.Append(toGrant)
+
+	for _, stmt := range ddl.List {
+		switch stmt.(type) {
+		case *ast.CreateProtoBundle, *ast.AlterProtoBundle:
+			ddl.ProtoDescriptors = g.to.rawProtoDescriptors
 		}
 	}
+	sorted, err := g.sortStatements(ddl.List)
+	if err != nil {
+		g.fail(err)
+	}
+	for i, stmt := range sorted {
+		sorted[i] = qualifyTableNames(stmt)
+	}
+	ddl.List = sorted
+	if g.err != nil {
+		return DDL{}, g.err
+	}
+	return ddl, nil
+}
 
-	return ddl
+// databaseOption describes how an option of ALTER DATABASE is reset when it is removed from the schema.
+type databaseOption struct {
+	// reset is the value resetting the option to its default, or nil if the option is reset by null.
+	reset ast.Expr
+	// fixed is true if the option cannot be reset once it is set.
+	fixed bool
+}
+
+// databaseOptions is the registry of the options of ALTER DATABASE.
+// Options not in the registry are reset by null.
+var databaseOptions = map[string]databaseOption{
+	"default_leader":               {},
+	"default_sequence_kind":        {fixed: true},
+	"default_time_zone":            {fixed: true},
+	"enable_key_visualizer":        {},
+	"optimizer_statistics_package": {},
+	"optimizer_version":            {},
+	"version_retention_period":     {},
+}
+
+// resetDatabaseOptions returns the options resetting the options of the source database which are not in the given options.
+func (g *Generator) resetDatabaseOptions(options *ast.Options) []*ast.OptionsDef {
+	var resets []*ast.OptionsDef
+	if g.from.options == nil {
+		return resets
+	}
+	for _, r := range g.from.options.Records {
+		name := r.Name.Name
+		if optionsValueFromName(options, name) != nil {
+			continue
+		}
+		if _, ok := r.Value.(*ast.NullLiteral); ok {
+			continue
+		}
+		option := databaseOptions[strings.ToLower(name)]
+		if option.fixed {
+			g.fail(g.errorf(r, "cannot remove database option %s: Spanner cannot reset it once it is set", name))
+			continue
+		}
+		var value ast.Expr = &ast.NullLiteral{}
+		if option.reset != nil {
+			value = option.reset
+		}
+		resets = append(resets, &ast.OptionsDef{Name: &ast.Ident{Name: name}, Value: value})
+	}
+	return resets
 }
 
 func (g *Generator) generateDDLForAlterDatabaseOptions() DDL {
@@ -421,43 +1113,20 @@ func (g *Generator) generateDDLForAlterDatabaseOptions() DDL {
 		return ddl
 	}
 	if g.to.alterDatabaseOptions == nil {
-		// set all null
+		resets := g.resetDatabaseOptions(nil)
+		if len(resets) == 0 {
+			return ddl
+		}
 		ddl.Append(&ast.AlterDatabase{
-			Name: g.from.alterDatabaseOptions.Name,
-			Options: &ast.Options{
-				Records: []*ast.OptionsDef{
-					{
-						Name:  &ast.Ident{Name: "optimizer_version"},
-						Value: &ast.NullLiteral{},
-					},
-					{
-						Name:  &ast.Ident{Name: "version_retention_period"},
-						Value: &ast.NullLiteral{},
-					},
-					{
-						Name:  &ast.Ident{Name: "enable_key_visualizer"},
-						Value: &ast.NullLiteral{},
-					},
-				},
-			},
+			Name:    g.from.alterDatabaseOptions.Name,
+			Options: &ast.Options{Records: resets},
 		})
 		return ddl
 	}
 
-	dbopts := g.to.alterDatabaseOptions.Options
-	if g.from.options != nil {
-		for _, r := range g.from.options.Records {
-			name := r.Name.Name
-			if _, ok := optionsTo[name]; ok {
-				continue
-			}
-			dbopts.Records = append(dbopts.Records, &ast.OptionsDef{
-				Name:  &ast.Ident{Name: name},
-				Value: &ast.NullLiteral{},
-			})
-		}
+	dbopts := &ast.Options{
+		Records: append(append([]*ast.OptionsDef{}, g.to.options.Records...), g.resetDatabaseOptions(g.to.options)...),
 	}
-
 	ddl.Append(&ast.AlterDatabase{
 		Name:    g.to.alterDatabaseOptions.Name,
 		Options: dbopts,
@@ -465,37 +1134,238 @@ func (g *Generator) generateDDLForAlterDatabaseOptions() DDL {
 	return ddl
 }
 
-func (g *Generator) generateDDLForCreateTableAndIndex(table *Table) DDL {
+func (g *Generator) generateDDLForCreateOrAlterProtoBundle() DDL {
+	ddl := DDL{}
+
+	if g.to.protoBundle == nil {
+		return ddl
+	}
+	if g.from.protoBundle == nil {
+		for _, t := range g.to.protoBundle.types {
+			g.requireProtoDescriptor(t)
+		}
+		ddl.Append(&ast.CreateProtoBundle{Types: &ast.ProtoBundleTypes{Types: g.to.protoBundle.types}})
+		return ddl
+	}
+
+	var inserts, updates []*ast.NamedType
+	for _, t := range g.to.protoBundle.types {
+		name := identsToComparable(t.Path...)
+		if _, exists := g.from.protoBundle.findType(name); !exists {
+			g.requireProtoDescriptor(t)
+			inserts = append(inserts, t)
+			continue
+		}
+		if !g.from.protoDescriptorEqual(g.to, name) {
+			g.requireProtoDescriptor(t)
+			updates = append(updates, t)
+		}
+	}
+	if len(inserts) == 0 && len(updates) == 0 {
+		return ddl
+	}
+	stmt := &ast.AlterProtoBundle{}
+	if len(inserts) > 0 {
+		stmt.Insert = &ast.AlterProtoBundleInsert{Types: &ast.ProtoBundleTypes{Types: inserts}}
+	}
+	if len(updates) > 0 {
+		stmt.Update = &ast.AlterProtoBundleUpdate{Types: &ast.ProtoBundleTypes{Types: updates}}
+	}
+	ddl.Append(stmt)
+	return ddl
+}
+
+// requireProtoDescriptor fails if the descriptor of the type to create or update is not given,
+// since Spanner rejects the statement without it.
+func (g *Generator) requireProtoDescriptor(t *ast.NamedType) {
+	if _, ok := g.to.protoDescriptors[identsToComparable(t.Path...)]; !ok {
+		g.fail(g.errorf(t, "proto descriptor of %s is required to create or update it in the proto bundle", t.SQL()))
+	}
+}
+
+func (g *Generator) generateDDLForDeleteOrDropProtoBundle() DDL {
+	ddl := DDL{}
+
+	if g.from.protoBundle == nil {
+		return ddl
+	}
+	if g.to.protoBundle == nil {
+		ddl.Append(&ast.DropProtoBundle{})
+		return ddl
+	}
+
+	var deletes []*ast.NamedType
+	for _, t := range g.from.protoBundle.types {
+		if _, exists := g.to.protoBundle.findType(identsToComparable(t.Path...)); !exists {
+			deletes = append(deletes, t)
+		}
+	}
+	if len(deletes) > 0 {
+		ddl.Append(&ast.AlterProtoBundle{Delete: &ast.AlterProtoBundleDelete{Types: &ast.ProtoBundleTypes{Types: deletes}}})
+	}
+	return ddl
+}
+
+func (g *Generator) generateDDLForCreateTableAndIndex(table *Table) DDL {
+	ddl := DDL{}
+
+	ddl.Append(table)
+	ddl.AppendDDL(g.generateDDLForCreateIndexAndChangeStream(table))
+	return ddl
+}
+
+func (g *Generator) generateDDLForCreateIndexAndChangeStream(table *Table) DDL {
+	ddl := DDL{}
+
+	for _, i := range table.indexes {
+		ddl.Append(i)
+	}
+	for _, i := range table.searchIndexes {
+		ddl.Append(i)
+	}
+	for _, i := range table.vectorIndexes {
+		ddl.Append(i)
+	}
+	for _, cs := range table.changeStreams {
+		g.willCreateOrAlterChangeStreamIDs[identsToComparable(cs.Name)] = cs
+	}
+	return ddl
+}
+
+// generateDDLForRebuildTable creates a shadow table with the new definition, copies the rows into it,
+// and swaps it with the existing table. If the shadow table is left by an interrupted rebuild, the copy is resumed.
+func (g *Generator) generateDDLForRebuildTable(from, to *Table) DDL {
+	ddl := DDL{}
+
+	shadowName := rebuildTableName(to.Name)
+	if _, exists := g.findTableByName(g.from.tables, identsToComparable(shadowName.Idents...)); !exists {
+		shadow := *to.CreateTable
+		shadow.Name = shadowName
+		shadow.TableConstraints = nil
+		shadow.Synonyms = nil
+		ddl.Append(&shadow)
+	}
+
+	var columns []*ast.Ident
+	for _, toCol := range to.Columns {
+		if _, ok := toCol.DefaultSemantics.(*ast.GeneratedColumnExpr); ok {
+			continue
+		}
+		if fromCol, exists := g.findColumnByName(from.Columns, identsToComparable(toCol.Name)); exists {
+			if _, ok := fromCol.DefaultSemantics.(*ast.GeneratedColumnExpr); !ok {
+				columns = append(columns, toCol.Name)
+			}
+		}
+	}
+	ddl.Append(CopyTable{From: from.Name, To: shadowName, Columns: columns, Keys: from.PrimaryKeys})
+
+	// the names are swapped at once, so that the name of the table always refers to a table.
+	// The existing table is dropped by the name it is swapped to.
+	ddl.AppendDDL(g.generateDDLForDropTableDependencies(from))
+	g.dropedTable = append(g.dropedTable, identsToComparable(from.Name.Idents...))
+	g.markRebuiltTable(shadowName)
+	oldName := suffixedTableName(to.Name, replacedTableSuffix)
+	ddl.Append(SwapTable{Table: to.Name, Shadow: shadowName, Old: oldName})
+	ddl.Append(&ast.DropTable{Name: oldName})
+	ddl.AppendDDL(g.generateDDLForRebuiltTableDependencies(to))
+	return ddl
+}
+
+// generateDDLForSwapRebuiltTable renames the shadow table left by an interrupted rebuild, which dropped the table
+// before renaming the shadow table, to the table name and recreates its dependencies.
+func (g *Generator) generateDDLForSwapRebuiltTable(to *Table) DDL {
+	ddl := DDL{}
+
+	shadowName := rebuildTableName(to.Name)
+	g.markRebuiltTable(shadowName)
+	ddl.Append(&ast.AlterTable{Name: shadowName, TableAlteration: &ast.RenameTo{Name: to.Name.Idents[len(to.Name.Idents)-1]}})
+	ddl.AppendDDL(g.generateDDLForRebuiltTableDependencies(to))
+	return ddl
+}
+
+func (g *Generator) markRebuiltTable(shadowName *ast.Path) {
+	if !g.isRebuiltTable(identsToComparable(shadowName.Idents...)) {
+		g.rebuiltTable = append(g.rebuiltTable, identsToComparable(shadowName.Idents...))
+	}
+}
+
+// generateDDLForRebuiltTableDependencies recreates the synonyms, the constraints, the indexes and the change streams
+// of the rebuilt table.
+func (g *Generator) generateDDLForRebuiltTableDependencies(to *Table) DDL {
 	ddl := DDL{}
 
-	ddl.Append(table)
-	for _, i := range table.indexes {
-		ddl.Append(i)
-	}
-	for _, i := range table.searchIndexes {
-		ddl.Append(i)
+	for _, s := range to.Synonyms {
+		ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddSynonym{Name: s.Name}})
 	}
-	for _, cs := range table.changeStreams {
-		g.willCreateOrAlterChangeStreamIDs[identsToComparable(cs.Name)] = cs
+	for _, c := range to.TableConstraints {
+		ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddTableConstraint{TableConstraint: c}})
 	}
+	ddl.AppendDDL(g.generateDDLForCreateIndexAndChangeStream(to))
 	return ddl
 }
 
+// validateRebuildTables returns an error if a table that requires rebuilding has interleaved tables,
+// since their rows cannot be preserved, or is interleaved in a new parent, since its rows may have no parent rows in it.
+func (g *Generator) validateRebuildTables() error {
+	for _, toTable := range g.to.tables {
+		fromTable, exists := g.findTableByName(g.from.tables, identsToComparable(toTable.Name.Idents...))
+		if !exists || !g.requireRecreateTable(fromTable, toTable) {
+			continue
+		}
+		if len(fromTable.children) > 0 {
+			return g.errorf(toTable.CreateTable, "cannot rebuild table %s with interleaved tables", toTable.Name.SQL())
+		}
+		if toTable.Cluster != nil && !g.interleaveParentEqual(fromTable, toTable) {
+			return g.errorf(toTable.CreateTable, "cannot rebuild table %s interleaved in a new parent %s", toTable.Name.SQL(), toTable.Cluster.TableName.SQL())
+		}
+	}
+	return nil
+}
+
+func rebuildTableName(name *ast.Path) *ast.Path {
+	return suffixedTableName(name, rebuildTableSuffix)
+}
+
+func suffixedTableName(name *ast.Path, suffix string) *ast.Path {
+	idents := append([]*ast.Ident{}, name.Idents[:len(name.Idents)-1]...)
+	last := name.Idents[len(name.Idents)-1]
+	return &ast.Path{Idents: append(idents, &ast.Ident{Name: last.Name + suffix})}
+}
+
 func (g *Generator) generateDDLForDropConstraintIndexAndTable(table *Table) DDL {
 	ddl := DDL{}
 
 	if g.isDropedTable(identsToComparable(table.Name.Idents...)) {
 		return ddl
 	}
+	ddl.AppendDDL(g.generateDDLForDropTableDependencies(table))
+	ddl.Append(&ast.DropTable{Name: table.Name})
+	g.dropedTable = append(g.dropedTable, identsToComparable(table.Name.Idents...))
+	return ddl
+}
+
+// generateDDLForDropTableDependencies drops the interleaved tables, the property graphs, the indexes, the change streams
+// and the foreign keys depending on the table, so that the table can be dropped.
+func (g *Generator) generateDDLForDropTableDependencies(table *Table) DDL {
+	ddl := DDL{}
+
 	for _, t := range table.children {
 		ddl.AppendDDL(g.generateDDLForDropConstraintIndexAndTable(t))
 	}
+	for _, pg := range g.from.propertyGraphs {
+		if pg.referencesTable(identsToComparable(table.Name.Idents...)) {
+			ddl.AppendDDL(g.generateDDLForDropPropertyGraph(pg))
+		}
+	}
 	for _, i := range table.indexes {
 		ddl.Append(&ast.DropIndex{Name: i.Name})
 	}
 	for _, i := range table.searchIndexes {
 		ddl.Append(&ast.DropSearchIndex{Name: i.Name})
 	}
+	for _, i := range table.vectorIndexes {
+		ddl.Append(&ast.DropVectorIndex{Name: i.Name})
+	}
 	for _, cs := range table.changeStreams {
 		if !g.isDropedChangeStream(identsToComparable(cs.Name)) {
 			if csFor, ok := cs.For.(*ast.ChangeStreamForTables); ok && len(csFor.Tables) > 1 {
@@ -563,8 +1433,6 @@ func (g *Generator) generateDDLForDropConstraintIndexAndTable(table *Table) DDL
 		}
 		g.droppedGrant = append(g.droppedGrant, grant)
 	}
-	ddl.Append(&ast.DropTable{Name: table.Name})
-	g.dropedTable = append(g.dropedTable, identsToComparable(table.Name.Idents...))
 	return ddl
 }
 
@@ -612,6 +1480,31 @@ func (g *Generator) generateDDLForConstraints(from, to *Table) DDL {
 	return ddl
 }
 
+func (g *Generator) generateDDLForInterleave(from, to *Table) DDL {
+	ddl := DDL{}
+
+	if from.Cluster == nil || to.Cluster == nil {
+		return ddl
+	}
+	switch {
+	case from.Cluster.Enforced != to.Cluster.Enforced:
+		ddl.Append(&ast.AlterTable{
+			Name: to.Name,
+			TableAlteration: &ast.SetInterleaveIn{
+				TableName: to.Cluster.TableName,
+				Enforced:  to.Cluster.Enforced,
+				OnDelete:  to.Cluster.OnDelete,
+			},
+		})
+	case onDeleteAction(from.Cluster) != onDeleteAction(to.Cluster):
+		ddl.Append(&ast.AlterTable{
+			Name:            to.Name,
+			TableAlteration: &ast.SetOnDelete{OnDelete: onDeleteAction(to.Cluster)},
+		})
+	}
+	return ddl
+}
+
 func (g *Generator) generateDDLForRowDeletionPolicy(from, to *Table) DDL {
 	ddl := DDL{}
 
@@ -646,16 +1539,43 @@ func (g *Generator) generateDDLForRowDeletionPolicy(from, to *Table) DDL {
 func (g *Generator) generateDDLForColumns(from, to *Table) DDL {
 	ddl := DDL{}
 
+	// Generated columns cannot be altered, so changed ones are dropped and added again.
+	// They are dropped before and added after the columns they depend on are changed.
+	recreated := g.findGeneratedColumnsToRecreate(from, to)
+	var createIndexes DDL
+	for _, fromCol := range recreated {
+		drop, create := g.generateDDLForDropColumnIndexes(from, fromCol)
+		for _, stmt := range drop.List {
+			switch stmt := stmt.(type) {
+			case *ast.DropIndex:
+				g.dropedIndex = append(g.dropedIndex, identsToComparable(stmt.Name.Idents...))
+			case *ast.DropSearchIndex:
+				g.dropedIndex = append(g.dropedIndex, identsToComparable(stmt.Name))
+			case *ast.DropVectorIndex:
+				g.dropedIndex = append(g.dropedIndex, identsToComparable(stmt.Name))
+			}
+		}
+		ddl.AppendDDL(drop)
+		createIndexes.AppendDDL(create)
+		ddl.AppendDDL(g.generateDDLForDropColumn(from.Name, fromCol.Name))
+	}
+	isRecreated := func(name *ast.Ident) bool {
+		_, exists := g.findColumnByName(recreated, identsToComparable(name))
+		return exists
+	}
+
 	for _, toCol := range to.Columns {
 		fromCol, exists := g.findColumnByName(from.Columns, identsToComparable(toCol.Name))
 
+		if isRecreated(toCol.Name) || (!exists && isGeneratedColumn(toCol)) {
+			continue
+		}
 		if !exists {
-			if toCol.NotNull && toCol.DefaultSemantics == nil {
-				ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: g.setDefaultSemantics(toCol)}})
-				ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AlterColumn{Name: toCol.Name, Alteration: &ast.AlterColumnDropDefault{}}})
-			} else {
-				ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: toCol}})
+			if renamedCol, exists := g.findRenamedColumn(from, to, toCol); exists {
+				ddl.AppendDDL(g.generateDDLForRenameColumn(to, renamedCol, toCol))
+				continue
 			}
+			ddl.AppendDDL(g.generateDDLForAddColumn(to.Name, toCol))
 			continue
 		}
 
@@ -668,39 +1588,37 @@ func (g *Generator) generateDDLForColumns(from, to *Table) DDL {
 			continue
 		}
 
-		requireDropAndCreateByDefault := func(d ast.ColumnDefaultSemantics) bool {
-			if d == nil {
-				return false
-			}
-			switch d.(type) {
-			case *ast.ColumnDefaultExpr:
-				return false
-			default:
-				return true
-			}
-		}
-		if g.columnTypeEqual(fromCol, toCol) && !requireDropAndCreateByDefault(fromCol.DefaultSemantics) && !requireDropAndCreateByDefault(toCol.DefaultSemantics) {
-			if st, ok := fromCol.Type.(*ast.ScalarSchemaType); ok && st.Name == ast.TimestampTypeName {
-				if fromCol.NotNull != toCol.NotNull || !g.columnDefaultExprEqual(fromCol.DefaultSemantics, toCol.DefaultSemantics) {
-					if !fromCol.NotNull && toCol.NotNull {
-						ddl.Append(Update{Table: to.Name.SQL(), Def: toCol})
-					}
-					ddl.Append(AlterColumn{Table: to.Name.SQL(), Def: toCol})
-				}
-				if !g.optionsValueEqual(fromCol.Options, toCol.Options, "allow_commit_timestamp") {
-					ddl.Append(AlterColumn{Table: to.Name.SQL(), Def: toCol, SetOptions: true})
-				}
-			} else {
+		typeAlterable := g.columnTypeEqual(fromCol, toCol) || g.columnTypeConvertible(fromCol.Type, toCol.Type)
+		defaultAlterable := !requireDropAndCreateByDefault(fromCol.DefaultSemantics) && !requireDropAndCreateByDefault(toCol.DefaultSemantics)
+		if typeAlterable && defaultAlterable {
+			if !g.columnDefEqualIgnoringOptions(fromCol, toCol) {
 				if !fromCol.NotNull && toCol.NotNull {
-					ddl.Append(Update{Table: to.Name.SQL(), Def: toCol})
+					ddl.AppendDDL(g.generateUpdateForNullValues(to.Name, toCol))
 				}
 				ddl.Append(AlterColumn{Table: to.Name.SQL(), Def: toCol})
 			}
+			if options := changedOptions(fromCol.Options, toCol.Options); len(options.Records) > 0 {
+				ddl.Append(AlterColumn{Table: to.Name.SQL(), Def: toCol, Options: options})
+			}
+		} else if g.option.ConvertColumnType && defaultAlterable && !isColHidden(toCol) && columnConvertExpr(fromCol, toCol) != "" {
+			ddl.AppendDDL(g.generateDDLForConvertColumn(from, to, fromCol, toCol))
 		} else {
 			ddl.AppendDDL(g.generateDDLForDropAndCreateColumn(from, to, fromCol, toCol))
 		}
 	}
+
+	for _, toCol := range to.Columns {
+		_, exists := g.findColumnByName(from.Columns, identsToComparable(toCol.Name))
+		if isRecreated(toCol.Name) || (!exists && isGeneratedColumn(toCol)) {
+			ddl.AppendDDL(g.generateDDLForAddColumn(to.Name, toCol))
+		}
+	}
+	ddl.AppendDDL(createIndexes)
+
 	for _, fromCol := range from.Columns {
+		if isRecreated(fromCol.Name) {
+			continue
+		}
 		if _, exists := g.findColumnByName(to.Columns, identsToComparable(fromCol.Name)); !exists {
 			ddl.AppendDDL(g.generateDDLForDropColumn(from.Name, fromCol.Name))
 		}
@@ -708,6 +1626,133 @@ func (g *Generator) generateDDLForColumns(from, to *Table) DDL {
 	return ddl
 }
 
+// findGeneratedColumnsToRecreate returns the columns in "from" that are dropped and added again,
+// which are the generated columns that are removed or changed, or depend on such columns or columns that cannot be altered,
+// and the columns that are changed from or to generated columns.
+func (g *Generator) findGeneratedColumnsToRecreate(from, to *Table) []*ast.ColumnDef {
+	var recreated []*ast.ColumnDef
+	willRecreate := func(name string) bool {
+		_, exists := g.findColumnByName(recreated, name)
+		return exists
+	}
+
+	for _, fromCol := range from.Columns {
+		toCol, exists := g.findColumnByName(to.Columns, identsToComparable(fromCol.Name))
+		if !isGeneratedColumn(fromCol) && (!exists || !isGeneratedColumn(toCol)) {
+			continue
+		}
+		if !exists || !g.columnDefEqual(fromCol, toCol) {
+			recreated = append(recreated, fromCol)
+		}
+	}
+	for {
+		found := false
+		for _, fromCol := range from.Columns {
+			if !isGeneratedColumn(fromCol) || willRecreate(identsToComparable(fromCol.Name)) {
+				continue
+			}
+			for _, dep := range generatedColumnDependencies(fromCol) {
+				depFrom, exists := g.findColumnByName(from.Columns, dep)
+				if !exists {
+					continue
+				}
+				depTo, exists := g.findColumnByName(to.Columns, dep)
+				if !exists || willRecreate(dep) || (!isGeneratedColumn(depFrom) && g.columnRequiresDropAndCreate(depFrom, depTo)) {
+					recreated = append(recreated, fromCol)
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return recreated
+		}
+	}
+}
+
+// columnRequiresDropAndCreate returns true if the column is dropped to change it.
+func (g *Generator) columnRequiresDropAndCreate(fromCol, toCol *ast.ColumnDef) bool {
+	if isColHidden(fromCol) != isColHidden(toCol) || g.columnDefEqual(fromCol, toCol) {
+		return false
+	}
+	typeAlterable := g.columnTypeEqual(fromCol, toCol) || g.columnTypeConvertible(fromCol.Type, toCol.Type)
+	defaultAlterable := !requireDropAndCreateByDefault(fromCol.DefaultSemantics) && !requireDropAndCreateByDefault(toCol.DefaultSemantics)
+	return !typeAlterable || !defaultAlterable
+}
+
+func requireDropAndCreateByDefault(d ast.ColumnDefaultSemantics) bool {
+	if d == nil {
+		return false
+	}
+	switch d.(type) {
+	case *ast.ColumnDefaultExpr:
+		return false
+	default:
+		return true
+	}
+}
+
+func isGeneratedColumn(col *ast.ColumnDef) bool {
+	_, ok := col.DefaultSemantics.(*ast.GeneratedColumnExpr)
+	return ok
+}
+
+// generatedColumnDependencies returns the names of the columns referenced by the generated column expression.
+func generatedColumnDependencies(col *ast.ColumnDef) []string {
+	expr, ok := col.DefaultSemantics.(*ast.GeneratedColumnExpr)
+	if !ok {
+		return nil
+	}
+	var deps []string
+	ast.Inspect(expr.Expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			deps = append(deps, ident.Name)
+		}
+		return true
+	})
+	return deps
+}
+
+func (g *Generator) generateDDLForAddColumn(table *ast.Path, col *ast.ColumnDef) DDL {
+	ddl := DDL{}
+
+	if col.NotNull && col.DefaultSemantics == nil {
+		def, err := g.setDefaultSemantics(col)
+		if err != nil {
+			g.fail(g.errorf(col, "%s", err))
+			return ddl
+		}
+		col := def
+		if col.DefaultSemantics != nil {
+			ddl.Append(&ast.AlterTable{Name: table, TableAlteration: &ast.AddColumn{Column: col}})
+			ddl.Append(&ast.AlterTable{Name: table, TableAlteration: &ast.AlterColumn{Name: col.Name, Alteration: &ast.AlterColumnDropDefault{}}})
+			return ddl
+		}
+	}
+	ddl.Append(&ast.AlterTable{Name: table, TableAlteration: &ast.AddColumn{Column: col}})
+	return ddl
+}
+
+func (g *Generator) generateUpdateForNullValues(table *ast.Path, col *ast.ColumnDef) DDL {
+	ddl := DDL{}
+
+	def := col
+	if t, ok := col.Type.(*ast.NamedType); ok && col.DefaultSemantics == nil {
+		if v := g.to.protoDefaultValue(t); v != nil {
+			copied := *col
+			copied.DefaultSemantics = &ast.ColumnDefaultExpr{Expr: v}
+			def = &copied
+		}
+	}
+	update, err := NewUpdate(table.SQL(), def)
+	if err != nil {
+		g.fail(g.errorf(col, "%s", err))
+		return ddl
+	}
+	ddl.Append(update)
+	return ddl
+}
+
 func (g *Generator) generateDDLForDropColumn(table *ast.Path, column *ast.Ident) DDL {
 	ddl := DDL{}
 
@@ -762,40 +1807,82 @@ func (g *Generator) generateDDLForDropColumn(table *ast.Path, column *ast.Ident)
 func (g *Generator) generateDDLForDropAndCreateColumn(from, to *Table, fromCol, toCol *ast.ColumnDef) DDL {
 	ddl := DDL{}
 
-	indexes := []*ast.CreateIndex{}
+	ddl.AppendDDL(g.generateDDLForDropColumn(from.Name, fromCol.Name))
+	ddl.AppendDDL(g.generateDDLForAddColumn(to.Name, toCol))
+	return g.generateDDLForRecreateColumnIndexes(from, fromCol, ddl)
+}
+
+// generateDDLForConvertColumn changes the column type that cannot be altered in place without losing the values.
+// The values are converted into a temporary column by partitioned DML, and copied back into the column
+// added again with the new type, since Spanner cannot rename columns.
+func (g *Generator) generateDDLForConvertColumn(from, to *Table, fromCol, toCol *ast.ColumnDef) DDL {
+	ddl := DDL{}
+
+	tmpCol := *toCol
+	tmpCol.Name = &ast.Ident{Name: toCol.Name.Name + convertColumnSuffix}
+	tmpCol.NotNull = false
+	newCol := *toCol
+	newCol.NotNull = false
+	ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: &tmpCol}})
+	ddl.Append(ConvertColumn{Table: to.Name, From: fromCol, To: &tmpCol})
+	ddl.AppendDDL(g.generateDDLForDropColumn(from.Name, fromCol.Name))
+	ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: &newCol}})
+	ddl.Append(ConvertColumn{Table: to.Name, From: &tmpCol, To: &newCol})
+	ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.DropColumn{Name: tmpCol.Name}})
+	if toCol.NotNull {
+		if !fromCol.NotNull {
+			ddl.AppendDDL(g.generateUpdateForNullValues(to.Name, toCol))
+		}
+		ddl.Append(AlterColumn{Table: to.Name.SQL(), Def: toCol})
+	}
+	return g.generateDDLForRecreateColumnIndexes(from, fromCol, ddl)
+}
+
+// generateDDLForRecreateColumnIndexes drops the indexes on the column before the ddl and creates them again after it.
+func (g *Generator) generateDDLForRecreateColumnIndexes(from *Table, fromCol *ast.ColumnDef, replace DDL) DDL {
+	ddl := DDL{}
+
+	drop, create := g.generateDDLForDropColumnIndexes(from, fromCol)
+	ddl.AppendDDL(drop)
+	ddl.AppendDDL(replace)
+	ddl.AppendDDL(create)
+	return ddl
+}
+
+// generateDDLForDropColumnIndexes drops the indexes on the column, and returns the DDL to create them again.
+func (g *Generator) generateDDLForDropColumnIndexes(from *Table, fromCol *ast.ColumnDef) (drop DDL, create DDL) {
 	for _, i := range g.findIndexByColumn(from.indexes, identsToComparable(fromCol.Name)) {
 		if !g.isDropedIndex(identsToComparable(i.Name.Idents...)) {
-			indexes = append(indexes, i)
+			drop.Append(&ast.DropIndex{Name: i.Name})
+			create.Append(i)
 		}
 	}
-	for _, i := range indexes {
-		ddl.Append(&ast.DropIndex{Name: i.Name})
-	}
-
-	searchIndexes := []*ast.CreateSearchIndex{}
 	for _, i := range g.findSearchIndexByColumn(from.searchIndexes, identsToComparable(fromCol.Name)) {
 		if !g.isDropedIndex(identsToComparable(i.Name)) {
-			searchIndexes = append(searchIndexes, i)
+			drop.Append(&ast.DropSearchIndex{Name: i.Name})
+			create.Append(i)
 		}
 	}
-	for _, i := range searchIndexes {
-		ddl.Append(&ast.DropSearchIndex{Name: i.Name})
+	for _, i := range g.findVectorIndexByColumn(from.vectorIndexes, identsToComparable(fromCol.Name)) {
+		if !g.isDropedIndex(identsToComparable(i.Name)) {
+			drop.Append(&ast.DropVectorIndex{Name: i.Name})
+			create.Append(i)
+		}
 	}
+	return drop, create
+}
 
-	ddl.AppendDDL(g.generateDDLForDropColumn(from.Name, fromCol.Name))
+func (g *Generator) generateDDLForTableOptions(from, to *Table) DDL {
+	ddl := DDL{}
 
-	if toCol.NotNull && toCol.DefaultSemantics == nil {
-		ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: g.setDefaultSemantics(toCol)}})
-		ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AlterColumn{Name: toCol.Name, Alteration: &ast.AlterColumnDropDefault{}}})
-	} else {
-		ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: toCol}})
-	}
-	for _, i := range indexes {
-		ddl.Append(i)
-	}
-	for _, i := range searchIndexes {
-		ddl.Append(i)
+	options := changedOptions(from.Options, to.Options)
+	if len(options.Records) == 0 {
+		return ddl
 	}
+	ddl.Append(&ast.AlterTable{
+		Name:            to.Name,
+		TableAlteration: &ast.AlterTableSetOptions{Options: options},
+	})
 	return ddl
 }
 
@@ -820,7 +1907,7 @@ func (g *Generator) generateDDLForDropIndex(from, to *Table) DDL {
 	for _, toIndex := range to.searchIndexes {
 		fromIndex, exists := g.findSearchIndexByName(from.searchIndexes, identsToComparable(toIndex.Name))
 
-		if exists && !g.searchIndexEqual(*fromIndex, *toIndex) {
+		if exists && !g.searchIndexEqualIgnoringStoring(fromIndex, toIndex) {
 			ddl.Append(&ast.DropSearchIndex{Name: fromIndex.Name})
 			g.dropedIndex = append(g.dropedIndex, identsToComparable(fromIndex.Name))
 		}
@@ -832,6 +1919,21 @@ func (g *Generator) generateDDLForDropIndex(from, to *Table) DDL {
 		}
 	}
 
+	for _, toIndex := range to.vectorIndexes {
+		fromIndex, exists := g.findVectorIndexByName(from.vectorIndexes, identsToComparable(toIndex.Name))
+
+		if exists && !g.vectorIndexEqual(fromIndex, toIndex) {
+			ddl.Append(&ast.DropVectorIndex{Name: fromIndex.Name})
+			g.dropedIndex = append(g.dropedIndex, identsToComparable(fromIndex.Name))
+		}
+	}
+	for _, fromIndex := range from.vectorIndexes {
+		if _, exists := g.findVectorIndexByName(to.vectorIndexes, identsToComparable(fromIndex.Name)); !exists {
+			ddl.Append(&ast.DropVectorIndex{Name: fromIndex.Name})
+			g.dropedIndex = append(g.dropedIndex, identsToComparable(fromIndex.Name))
+		}
+	}
+
 	return ddl
 }
 
@@ -849,7 +1951,15 @@ func (g *Generator) generateDDLForCreateIndex(from, to *Table) DDL {
 	for _, toIndex := range to.searchIndexes {
 		fromIndex, exists := g.findSearchIndexByName(from.searchIndexes, identsToComparable(toIndex.Name))
 
-		if !exists || !g.searchIndexEqual(*fromIndex, *toIndex) {
+		if !exists || !g.searchIndexEqualIgnoringStoring(fromIndex, toIndex) {
+			ddl.Append(toIndex)
+		}
+	}
+
+	for _, toIndex := range to.vectorIndexes {
+		fromIndex, exists := g.findVectorIndexByName(from.vectorIndexes, identsToComparable(toIndex.Name))
+
+		if !exists || !g.vectorIndexEqual(fromIndex, toIndex) {
 			ddl.Append(toIndex)
 		}
 	}
@@ -910,9 +2020,46 @@ func (g *Generator) generateDDLForAlterIndex(from, to *Table) DDL {
 		}
 	}
 
+	for _, toIndex := range to.searchIndexes {
+		fromIndex, exists := g.findSearchIndexByName(from.searchIndexes, identsToComparable(toIndex.Name))
+		if !exists || !g.searchIndexEqualIgnoringStoring(fromIndex, toIndex) {
+			continue
+		}
+
+		for _, toIndexStoringColumn := range storingColumns(toIndex.Storing) {
+			if _, exists := g.findIdentByName(storingColumns(fromIndex.Storing), identsToComparable(toIndexStoringColumn)); exists {
+				continue
+			}
+			ddl.Append(&ast.AlterSearchIndex{
+				Name: toIndex.Name,
+				IndexAlteration: &ast.AddStoredColumn{
+					Name: toIndexStoringColumn,
+				},
+			})
+		}
+		for _, fromIndexStoringColumn := range storingColumns(fromIndex.Storing) {
+			if _, exists := g.findIdentByName(storingColumns(toIndex.Storing), identsToComparable(fromIndexStoringColumn)); exists {
+				continue
+			}
+			ddl.Append(&ast.AlterSearchIndex{
+				Name: toIndex.Name,
+				IndexAlteration: &ast.DropStoredColumn{
+					Name: fromIndexStoringColumn,
+				},
+			})
+		}
+	}
+
 	return ddl
 }
 
+func storingColumns(storing *ast.Storing) []*ast.Ident {
+	if storing == nil {
+		return nil
+	}
+	return storing.Columns
+}
+
 func (g *Generator) generateDDLForCreateChangeStream(from *Database, to *Table) DDL {
 	ddl := DDL{}
 
@@ -955,6 +2102,25 @@ func (g *Generator) isDropedTable(name string) bool {
 	return false
 }
 
+// isRenamedTable returns true if the table or its columns are renamed. Both old and new names of the table match.
+func (g *Generator) isRenamedTable(name string) bool {
+	for _, t := range g.renamedTable {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) isRebuiltTable(name string) bool {
+	for _, t := range g.rebuiltTable {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Generator) isDropedIndex(name string) bool {
 	for _, t := range g.dropedIndex {
 		if t == name {
@@ -982,6 +2148,24 @@ func (g *Generator) isDropedChangeStream(name string) bool {
 	return false
 }
 
+func (g *Generator) isDroppedView(name string) bool {
+	for _, v := range g.droppedView {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) isDroppedPropertyGraph(name string) bool {
+	for _, pg := range g.droppedPropertyGraph {
+		if strings.EqualFold(pg, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Generator) isDroppedGrant(grant *Grant) bool {
 	for _, dg := range g.droppedGrant {
 		if equalGrant(dg, grant) {
@@ -991,8 +2175,41 @@ func (g *Generator) isDroppedGrant(grant *Grant) bool {
 	return false
 }
 
-func (g *Generator) interleaveEqual(x, y *Table) bool {
-	return cmp.Equal(x.Cluster, y.Cluster, cmpopts.IgnoreTypes(token.Pos(0)))
+// requireRecreateTable returns true if the table cannot be altered in place.
+// Changes of the interleave kind or ON DELETE action are applied by ALTER TABLE, but not changes of the parent.
+func (g *Generator) requireRecreateTable(from, to *Table) bool {
+	return !g.interleaveParentEqual(from, to) || !g.primaryKeyEqual(from, to)
+}
+
+// willDropTable returns true if the table in "from(Database)" is dropped by GenerateDDL,
+// either because it no longer exists, or because it or one of its ancestors is recreated.
+func (g *Generator) willDropTable(name string) bool {
+	fromTable, exists := g.findTableByName(g.from.tables, name)
+	if !exists {
+		return false
+	}
+	toTable, exists := g.findTableByName(g.to.tables, name)
+	if !exists || g.requireRecreateTable(fromTable, toTable) {
+		return true
+	}
+	if fromTable.Cluster != nil {
+		return g.willDropTable(identsToComparable(fromTable.Cluster.TableName.Idents...))
+	}
+	return false
+}
+
+func (g *Generator) interleaveParentEqual(x, y *Table) bool {
+	if x.Cluster == nil || y.Cluster == nil {
+		return x.Cluster == nil && y.Cluster == nil
+	}
+	return strings.EqualFold(identsToComparable(x.Cluster.TableName.Idents...), identsToComparable(y.Cluster.TableName.Idents...))
+}
+
+func onDeleteAction(c *ast.Cluster) ast.OnDeleteAction {
+	if c.OnDelete == "" {
+		return ast.OnDeleteNoAction
+	}
+	return c.OnDelete
 }
 
 func (g *Generator) primaryKeyEqual(x, y *Table) bool {
@@ -1039,6 +2256,12 @@ func (g *Generator) columnDefEqual(x, y *ast.ColumnDef) bool {
 	)
 }
 
+func (g *Generator) columnDefEqualIgnoringOptions(x, y *ast.ColumnDef) bool {
+	xc, yc := *x, *y
+	xc.Options, yc.Options = nil, nil
+	return g.columnDefEqual(&xc, &yc)
+}
+
 func (g *Generator) columnTypeEqual(x, y *ast.ColumnDef) bool {
 	return cmp.Equal(x.Type, y.Type,
 		cmpopts.IgnoreTypes(token.Pos(0)),
@@ -1048,6 +2271,38 @@ func (g *Generator) columnTypeEqual(x, y *ast.ColumnDef) bool {
 	)
 }
 
+// columnTypeConvertible returns true if Spanner can change the column type from x to y by ALTER COLUMN.
+func (g *Generator) columnTypeConvertible(x, y ast.SchemaType) bool {
+	xArray, xIsArray := x.(*ast.ArraySchemaType)
+	yArray, yIsArray := y.(*ast.ArraySchemaType)
+	if xIsArray || yIsArray {
+		return xIsArray && yIsArray && g.columnTypeConvertible(xArray.Item, yArray.Item)
+	}
+	switch x := x.(type) {
+	case *ast.SizedSchemaType:
+		switch y := y.(type) {
+		case *ast.SizedSchemaType:
+			return true
+		case *ast.NamedType:
+			return x.Name == ast.BytesTypeName && !g.to.isProtoEnum(y)
+		}
+	case *ast.ScalarSchemaType:
+		if y, ok := y.(*ast.NamedType); ok {
+			return x.Name == ast.Int64TypeName && !g.to.isProtoMessage(y)
+		}
+	case *ast.NamedType:
+		switch y := y.(type) {
+		case *ast.SizedSchemaType:
+			return y.Name == ast.BytesTypeName && !g.from.isProtoEnum(x)
+		case *ast.ScalarSchemaType:
+			return y.Name == ast.Int64TypeName && !g.from.isProtoMessage(x)
+		case *ast.NamedType:
+			return identsToComparable(x.Path...) == identsToComparable(y.Path...)
+		}
+	}
+	return false
+}
+
 func (g *Generator) constraintEqual(x, y *ast.TableConstraint) bool {
 	return cmp.Equal(x, y,
 		cmpopts.IgnoreTypes(token.Pos(0)),
@@ -1066,9 +2321,13 @@ func (g *Generator) constraintEqual(x, y *ast.TableConstraint) bool {
 }
 
 func (g *Generator) indexEqualIgnoringStoring(x, y *ast.CreateIndex) bool {
+	identComparer := cmp.Comparer(func(x, y *ast.Ident) bool {
+		return strings.EqualFold(x.Name, y.Name)
+	})
 	return cmp.Equal(x, y,
 		cmpopts.IgnoreTypes(token.Pos(0)),
 		cmpopts.IgnoreTypes(&ast.Storing{}),
+		identComparer,
 		cmp.Comparer(func(a, b *ast.IndexKey) bool {
 			aVal := *a
 			bVal := *b
@@ -1078,12 +2337,50 @@ func (g *Generator) indexEqualIgnoringStoring(x, y *ast.CreateIndex) bool {
 			if bVal.Dir == "" {
 				bVal.Dir = ast.DirectionAsc
 			}
-			return cmp.Equal(aVal, bVal, cmpopts.IgnoreTypes(token.Pos(0)))
+			return cmp.Equal(aVal, bVal, cmpopts.IgnoreTypes(token.Pos(0)), identComparer)
+		}),
+	)
+}
+
+// searchIndexEqualIgnoringStoring returns true if the search indexes are equal except for
+// the stored columns, which can be altered without recreating the index.
+// ALTER SEARCH INDEX only supports ADD STORED COLUMN and DROP STORED COLUMN, so any other change,
+// including a change of the options, requires recreating the index.
+func (g *Generator) searchIndexEqualIgnoringStoring(x, y *ast.CreateSearchIndex) bool {
+	return cmp.Equal(x, y,
+		cmpopts.IgnoreTypes(token.Pos(0)),
+		cmpopts.IgnoreTypes(&ast.Storing{}),
+	)
+}
+
+func (g *Generator) viewEqual(x, y *View) bool {
+	if x.SecurityType != y.SecurityType {
+		return false
+	}
+	return cmp.Equal(x.Query, y.Query,
+		cmpopts.IgnoreTypes(token.Pos(0)),
+		cmp.Comparer(func(x, y *ast.Ident) bool {
+			if x == nil || y == nil {
+				return x == y
+			}
+			return strings.EqualFold(x.Name, y.Name)
+		}),
+	)
+}
+
+func (g *Generator) propertyGraphEqual(x, y *PropertyGraph) bool {
+	return cmp.Equal(x.Content, y.Content,
+		cmpopts.IgnoreTypes(token.Pos(0)),
+		cmp.Comparer(func(x, y *ast.Ident) bool {
+			if x == nil || y == nil {
+				return x == y
+			}
+			return strings.EqualFold(x.Name, y.Name)
 		}),
 	)
 }
 
-func (g *Generator) searchIndexEqual(x, y ast.CreateSearchIndex) bool {
+func (g *Generator) vectorIndexEqual(x, y *ast.CreateVectorIndex) bool {
 	return cmp.Equal(x, y, cmpopts.IgnoreTypes(token.Pos(0)))
 }
 
@@ -1103,10 +2400,16 @@ func (g *Generator) optionsEqual(x, y *ast.Options) bool {
 	return cmp.Equal(x, y, cmpopts.IgnoreTypes(token.Pos(0)))
 }
 
-func (g *Generator) optionsValueEqual(x, y *ast.Options, name string) bool {
-	xv := optionsValueFromName(x, name)
-	yv := optionsValueFromName(y, name)
-	return cmp.Equal(xv, yv, cmpopts.IgnoreTypes(token.Pos(0)))
+func (g *Generator) modelInputOutputEqual(x, y *Model) bool {
+	if x.Remote.Invalid() != y.Remote.Invalid() {
+		return false
+	}
+	return cmp.Equal(x.InputOutput, y.InputOutput,
+		cmpopts.IgnoreTypes(token.Pos(0)),
+		cmp.Comparer(func(x, y *ast.Ident) bool {
+			return strings.EqualFold(x.Name, y.Name)
+		}),
+	)
 }
 
 func optionsValueFromName(options *ast.Options, name string) *ast.Expr {
@@ -1121,6 +2424,31 @@ func optionsValueFromName(options *ast.Options, name string) *ast.Expr {
 	return nil
 }
 
+// changedOptions returns the options to be set in order to change from into to.
+// Options whose value is unchanged are omitted, and options that only exist in from are reset by setting them to null.
+func changedOptions(from, to *ast.Options) *ast.Options {
+	options := &ast.Options{}
+	if to != nil {
+		for _, r := range to.Records {
+			if v := optionsValueFromName(from, r.Name.Name); v != nil && (*v).SQL() == r.Value.SQL() {
+				continue
+			}
+			options.Records = append(options.Records, r)
+		}
+	}
+	if from != nil {
+		for _, r := range from.Records {
+			if optionsValueFromName(to, r.Name.Name) == nil {
+				options.Records = append(options.Records, &ast.OptionsDef{
+					Name:  &ast.Ident{Name: r.Name.Name},
+					Value: &ast.NullLiteral{},
+				})
+			}
+		}
+	}
+	return options
+}
+
 func defaultByScalarTypeName(t ast.ScalarTypeName) ast.Expr {
 	switch t {
 	case ast.BoolTypeName:
@@ -1159,7 +2487,9 @@ func identsToComparable(is ...*ast.Ident) string {
 	return b.String()
 }
 
-func (g *Generator) setDefaultSemantics(col *ast.ColumnDef) *ast.ColumnDef {
+// setDefaultSemantics sets the zero value of the type as the default value of the column.
+// It returns an error if the type is a proto or an enum whose descriptor is not given.
+func (g *Generator) setDefaultSemantics(col *ast.ColumnDef) (*ast.ColumnDef, error) {
 	switch t := col.Type.(type) {
 	case *ast.ArraySchemaType:
 		col.DefaultSemantics = &ast.ColumnDefaultExpr{Expr: &ast.ArrayLiteral{Values: nil}}
@@ -1168,10 +2498,15 @@ func (g *Generator) setDefaultSemantics(col *ast.ColumnDef) *ast.ColumnDef {
 	case *ast.SizedSchemaType:
 		col.DefaultSemantics = &ast.ColumnDefaultExpr{Expr: defaultByScalarTypeName(t.Name)}
 	case *ast.NamedType:
-		panic("not implemented")
+		// The zero value of proto and enum types can only be determined from the proto descriptors.
+		v := g.to.protoDefaultValue(t)
+		if v == nil {
+			return nil, fmt.Errorf("cannot add NOT NULL column %s without a default value: proto descriptor of %s is required", col.Name.SQL(), t.SQL())
+		}
+		col.DefaultSemantics = &ast.ColumnDefaultExpr{Expr: v}
 	}
 
-	return col
+	return col, nil
 }
 
 func (g *Generator) findTableByName(tables []*Table, name string) (table *Table, exists bool) {
@@ -1236,7 +2571,7 @@ func (g *Generator) findUnnamedConstraint(constraints []*ast.TableConstraint, it
 
 func (g *Generator) findIndexByName(indexes []*ast.CreateIndex, name string) (index *ast.CreateIndex, exists bool) {
 	for _, i := range indexes {
-		if identsToComparable(i.Name.Idents...) == name {
+		if strings.EqualFold(identsToComparable(i.Name.Idents...), name) {
 			return i, true
 		}
 	}
@@ -1258,7 +2593,7 @@ func (g *Generator) findIndexByColumn(indexes []*ast.CreateIndex, column string)
 
 func (g *Generator) findSearchIndexByName(indexes []*ast.CreateSearchIndex, name string) (index *ast.CreateSearchIndex, exists bool) {
 	for _, i := range indexes {
-		if identsToComparable(i.Name) == name {
+		if strings.EqualFold(identsToComparable(i.Name), name) {
 			return i, true
 		}
 	}
@@ -1278,6 +2613,25 @@ func (g *Generator) findSearchIndexByColumn(indexes []*ast.CreateSearchIndex, co
 	return result
 }
 
+func (g *Generator) findVectorIndexByName(indexes []*ast.CreateVectorIndex, name string) (index *ast.CreateVectorIndex, exists bool) {
+	for _, i := range indexes {
+		if strings.EqualFold(identsToComparable(i.Name), name) {
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+func (g *Generator) findVectorIndexByColumn(indexes []*ast.CreateVectorIndex, column string) []*ast.CreateVectorIndex {
+	result := []*ast.CreateVectorIndex{}
+	for _, i := range indexes {
+		if strings.EqualFold(identsToComparable(i.ColumnName), column) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
 func (g *Generator) generateDDLForDropNamedConstraintsMatchingPredicate(predicate func(table *Table, constraint *ast.TableConstraint) bool) DDL {
 	ddl := DDL{}
 
@@ -1294,7 +2648,7 @@ func (g *Generator) generateDDLForDropNamedConstraintsMatchingPredicate(predicat
 
 func (g *Generator) findChangeStreamByName(database *Database, name string) (changeStream *ChangeStream, exists bool) {
 	for _, cs := range database.changeStreams {
-		if identsToComparable(cs.Name) == name {
+		if strings.EqualFold(identsToComparable(cs.Name), name) {
 			changeStream = cs
 			exists = true
 			break
@@ -1302,7 +2656,7 @@ func (g *Generator) findChangeStreamByName(database *Database, name string) (cha
 	}
 	for _, table := range database.tables {
 		for _, cs := range table.changeStreams {
-			if identsToComparable(cs.Name) == name {
+			if strings.EqualFold(identsToComparable(cs.Name), name) {
 				changeStream = cs
 				exists = true
 				break
@@ -1370,7 +2724,7 @@ func (g *Generator) generateDDLForDropChangeStream(changeStream *ChangeStream) D
 
 func (g *Generator) findViewByName(views []*View, name string) (view *View, exists bool) {
 	for _, v := range views {
-		if identsToComparable(v.Name.Idents...) == name {
+		if strings.EqualFold(identsToComparable(v.Name.Idents...), name) {
 			view = v
 			exists = true
 			break
@@ -1381,7 +2735,40 @@ func (g *Generator) findViewByName(views []*View, name string) (view *View, exis
 
 func (g *Generator) generateDDLForReplaceView(view *View) DDL {
 	ddl := DDL{}
-	ddl.Append(&ast.CreateView{Name: view.Name, Query: view.Query, SecurityType: ast.SecurityTypeInvoker, OrReplace: true})
+	ddl.Append(&ast.CreateView{Name: view.Name, Query: view.Query, SecurityType: view.SecurityType, OrReplace: true})
+	return ddl
+}
+
+// generateDDLForDropViews drops the views that no longer exist, and the views that read from
+// tables or views that will be dropped. Dependent views are dropped before the views they read from.
+func (g *Generator) generateDDLForDropViews() DDL {
+	ddl := DDL{}
+
+	views := sortViewsByDependency(g.from.views)
+	var drops []*View
+	for _, view := range views {
+		name := identsToComparable(view.Name.Idents...)
+		drop := false
+		if _, exists := g.findViewByName(g.to.views, name); !exists {
+			drop = true
+		}
+		for _, ref := range view.references() {
+			if g.willDropTable(ref) || g.isRenamedTable(ref) {
+				drop = true
+			}
+			for _, d := range drops {
+				if strings.EqualFold(identsToComparable(d.Name.Idents...), ref) {
+					drop = true
+				}
+			}
+		}
+		if drop {
+			drops = append(drops, view)
+		}
+	}
+	for i := len(drops) - 1; i >= 0; i-- {
+		ddl.AppendDDL(g.generateDDLForDropView(drops[i]))
+	}
 	return ddl
 }
 
@@ -1396,6 +2783,124 @@ func (g *Generator) generateDDLForDropView(view *View) DDL {
 	}
 
 	ddl.Append(&ast.DropView{Name: view.Name})
+	g.droppedView = append(g.droppedView, identsToComparable(view.Name.Idents...))
+	return ddl
+}
+
+func (g *Generator) findSchemaByName(schemas []*Schema, name string) (schema *Schema, exists bool) {
+	for _, s := range schemas {
+		if strings.EqualFold(identsToComparable(s.Name), name) {
+			schema = s
+			exists = true
+			break
+		}
+	}
+	return
+}
+
+func (g *Generator) findSequenceByName(sequences []*Sequence, name string) (sequence *Sequence, exists bool) {
+	for _, s := range sequences {
+		if strings.EqualFold(identsToComparable(s.Name.Idents...), name) {
+			sequence = s
+			exists = true
+			break
+		}
+	}
+	return
+}
+
+func (g *Generator) generateDDLForAlterSequence(from, to *Sequence) DDL {
+	ddl := DDL{}
+
+	options := changedOptions(from.normalizedOptions(), to.normalizedOptions())
+	if len(options.Records) == 0 {
+		return ddl
+	}
+	ddl.Append(&ast.AlterSequence{
+		Name:    to.Name,
+		Options: options,
+	})
+	return ddl
+}
+
+func (g *Generator) findLocalityGroupByName(groups []*LocalityGroup, name string) (group *LocalityGroup, exists bool) {
+	for _, lg := range groups {
+		if strings.EqualFold(lg.Name.Name, name) {
+			group = lg
+			exists = true
+			break
+		}
+	}
+	return
+}
+
+func (g *Generator) generateDDLForAlterLocalityGroup(name *ast.Ident, from, to *ast.Options) DDL {
+	ddl := DDL{}
+
+	options := changedOptions(from, to)
+	if len(options.Records) == 0 {
+		return ddl
+	}
+	ddl.Append(&ast.AlterLocalityGroup{
+		Name:    name,
+		Options: options,
+	})
+	return ddl
+}
+
+func (g *Generator) findPropertyGraphByName(graphs []*PropertyGraph, name string) (graph *PropertyGraph, exists bool) {
+	for _, pg := range graphs {
+		if strings.EqualFold(identsToComparable(pg.Name), name) {
+			graph = pg
+			exists = true
+			break
+		}
+	}
+	return
+}
+
+func (g *Generator) generateDDLForDropPropertyGraph(graph *PropertyGraph) DDL {
+	ddl := DDL{}
+
+	if g.isDroppedPropertyGraph(identsToComparable(graph.Name)) {
+		return ddl
+	}
+	g.droppedPropertyGraph = append(g.droppedPropertyGraph, identsToComparable(graph.Name))
+
+	ddl.Append(&ast.DropPropertyGraph{Name: graph.Name})
+	return ddl
+}
+
+func (g *Generator) findModelByName(models []*Model, name string) (model *Model, exists bool) {
+	for _, m := range models {
+		if strings.EqualFold(identsToComparable(m.Name), name) {
+			model = m
+			exists = true
+			break
+		}
+	}
+	return
+}
+
+func (g *Generator) generateDDLForAlterModel(from, to *Model) DDL {
+	ddl := DDL{}
+
+	if !g.modelInputOutputEqual(from, to) {
+		ddl.Append(&ast.CreateModel{
+			OrReplace:   true,
+			Name:        to.Name,
+			InputOutput: to.InputOutput,
+			Remote:      to.Remote,
+			Options:     to.Options,
+		})
+		return ddl
+	}
+	if !g.optionsEqual(from.Options, to.Options) {
+		ddl.Append(&ast.AlterModel{
+			Name:    to.Name,
+			Options: changedOptions(from.Options, to.Options),
+		})
+	}
 	return ddl
 }
 
@@ -1405,7 +2910,7 @@ func isColHidden(col *ast.ColumnDef) bool {
 
 func (g *Generator) findRoleByName(roles []*Role, name string) (role *Role, exists bool) {
 	for _, r := range roles {
-		if identsToComparable(r.Name) == name {
+		if strings.EqualFold(identsToComparable(r.Name), name) {
 			role = r
 			exists = true
 			break
@@ -1416,7 +2921,7 @@ func (g *Generator) findRoleByName(roles []*Role, name string) (role *Role, exis
 
 func (g *Generator) generateDDLForDropRole(role *Role) DDL {
 	ddl := DDL{}
-	grants := g.from.grantsOnRole(role)
+	grants := append(g.from.grantsOfRole(role), g.from.grantsOnRole(role)...)
 
 	for _, grant := range grants {
 		if g.isDroppedGrant(grant) {
@@ -1426,7 +2931,8 @@ func (g *Generator) generateDDLForDropRole(role *Role) DDL {
 
 		// If the resource doesn't exist in "to(Database)", skip REVOKE:
 		// dropping the object means the grant no longer applies. Record droppedGrant only.
-		if !g.existsGrantResourceIn(grant, g.to) {
+		// Role memberships are always revoked, since they are not removed by dropping the other role.
+		if _, ok := grant.Privilege.(*ast.RolePrivilege); !ok && !g.existsGrantResourceIn(grant, g.to) {
 			continue
 		}
 		ddl.AppendDDL(g.generateDDLForRevokeAll(grant))
@@ -1437,17 +2943,6 @@ func (g *Generator) generateDDLForDropRole(role *Role) DDL {
 	return ddl
 }
 
-func (g *Generator) findGrant(grants []*Grant, grant *Grant) (grantRole *Grant, exists bool) {
-	for _, grt := range grants {
-		if equalGrant(grt, grant) {
-			grantRole = grt
-			exists = true
-			break
-		}
-	}
-	return
-}
-
 func (g *Generator) generateDDLForRevokeAll(grant *Grant) DDL {
 	ddl := DDL{}
 	if len(grant.Roles) == 0 {
@@ -1463,6 +2958,267 @@ func (g *Generator) generateDDLForRevokeAll(grant *Grant) DDL {
 	return ddl
 }
 
+// generateDDLForGrants revokes and grants only the privileges that differ.
+// A grant whose privileges all differ is revoked or granted as written,
+// otherwise only the differing privileges are revoked or granted.
+func (g *Generator) generateDDLForGrants() DDL {
+	ddl := DDL{}
+
+	granted := make(map[string]bool)
+	for _, fromGrant := range g.from.grants {
+		if g.isDroppedGrant(fromGrant) {
+			continue
+		}
+		for _, t := range grantTuples(fromGrant) {
+			granted[t.key()] = true
+		}
+	}
+	wanted := make(map[string]bool)
+	for _, toGrant := range g.to.grants {
+		for _, t := range grantTuples(toGrant) {
+			wanted[t.key()] = true
+		}
+	}
+
+	revoked := make(map[string]bool)
+	for _, fromGrant := range g.from.grants {
+		if g.isDroppedGrant(fromGrant) {
+			continue
+		}
+		tuples := grantTuples(fromGrant)
+		var revokes []grantTuple
+		for _, t := range tuples {
+			if !wanted[t.key()] && !revoked[t.key()] {
+				revokes = append(revokes, t)
+				revoked[t.key()] = true
+			}
+		}
+		if len(revokes) == 0 {
+			continue
+		}
+		if len(revokes) == len(tuples) {
+			ddl.AppendDDL(g.generateDDLForRevokeAll(fromGrant))
+			continue
+		}
+		for _, grant := range grantsFromTuples(revokes) {
+			ddl.Append(&ast.Revoke{Privilege: grant.Privilege, Roles: grant.Roles})
+		}
+	}
+	for _, toGrant := range g.to.grants {
+		tuples := grantTuples(toGrant)
+		var grants []grantTuple
+		for _, t := range tuples {
+			if !granted[t.key()] {
+				grants = append(grants, t)
+				granted[t.key()] = true
+			}
+		}
+		if len(grants) == 0 {
+			continue
+		}
+		if len(grants) == len(tuples) {
+			ddl.Append(toGrant)
+			continue
+		}
+		for _, grant := range grantsFromTuples(grants) {
+			ddl.Append(grant)
+		}
+	}
+	return ddl
+}
+
+// grantTuple is a single privilege of a grant: a privilege on an object, or on a column of it, granted to a role.
+type grantTuple struct {
+	// privilege is SELECT, INSERT, UPDATE, DELETE, EXECUTE or ROLE.
+	privilege string
+	// object is the kind of the object: TABLE, VIEW, CHANGE STREAM, TABLE FUNCTION or ROLE.
+	object string
+	name   *ast.Ident
+	// column is nil unless the privilege is granted on a column.
+	column *ast.Ident
+	role   *ast.Ident
+}
+
+func (t grantTuple) key() string {
+	column := ""
+	if t.column != nil {
+		column = t.column.Name
+	}
+	return strings.ToLower(strings.Join([]string{t.privilege, t.object, t.name.Name, column, t.role.Name}, "\x00"))
+}
+
+// grantTuples splits the grant into the privileges granted to each role on each object and column.
+func grantTuples(grant *Grant) []grantTuple {
+	type privilege struct {
+		privilege string
+		object    string
+		names     []*ast.Ident
+		columns   []*ast.Ident
+	}
+	var privileges []privilege
+	switch p := grant.Privilege.(type) {
+	case *ast.PrivilegeOnTable:
+		for _, tp := range p.Privileges {
+			switch tp := tp.(type) {
+			case *ast.SelectPrivilege:
+				privileges = append(privileges, privilege{"SELECT", "TABLE", p.Names, tp.Columns})
+			case *ast.InsertPrivilege:
+				privileges = append(privileges, privilege{"INSERT", "TABLE", p.Names, tp.Columns})
+			case *ast.UpdatePrivilege:
+				privileges = append(privileges, privilege{"UPDATE", "TABLE", p.Names, tp.Columns})
+			case *ast.DeletePrivilege:
+				privileges = append(privileges, privilege{"DELETE", "TABLE", p.Names, nil})
+			}
+		}
+	case *ast.SelectPrivilegeOnView:
+		privileges = append(privileges, privilege{"SELECT", "VIEW", p.Names, nil})
+	case *ast.SelectPrivilegeOnChangeStream:
+		privileges = append(privileges, privilege{"SELECT", "CHANGE STREAM", p.Names, nil})
+	case *ast.ExecutePrivilegeOnTableFunction:
+		privileges = append(privileges, privilege{"EXECUTE", "TABLE FUNCTION", p.Names, nil})
+	case *ast.RolePrivilege:
+		privileges = append(privileges, privilege{"ROLE", "ROLE", p.Names, nil})
+	}
+
+	var tuples []grantTuple
+	for _, p := range privileges {
+		for _, name := range p.names {
+			for _, role := range grant.Roles {
+				if len(p.columns) == 0 {
+					tuples = append(tuples, grantTuple{privilege: p.privilege, object: p.object, name: name, role: role})
+					continue
+				}
+				for _, column := range p.columns {
+					tuples = append(tuples, grantTuple{privilege: p.privilege, object: p.object, name: name, column: column, role: role})
+				}
+			}
+		}
+	}
+	return tuples
+}
+
+// grantsFromTuples groups the tuples into grants. Tuples on the same object granted to the same role are combined,
+// and then the grants with the same privileges are combined across roles and objects.
+func grantsFromTuples(tuples []grantTuple) []*ast.Grant {
+	type group struct {
+		tuples []grantTuple
+		names  []*ast.Ident
+		roles  []*ast.Ident
+	}
+	signature := func(gr *group) string {
+		var b strings.Builder
+		b.WriteString(gr.tuples[0].object)
+		for _, t := range gr.tuples {
+			if t.name == gr.tuples[0].name && t.role == gr.tuples[0].role {
+				b.WriteString("\x00" + t.privilege)
+				if t.column != nil {
+					b.WriteString("(" + strings.ToLower(t.column.Name) + ")")
+				}
+			}
+		}
+		return b.String()
+	}
+	merge := func(groups []*group, key func(*group) string, combine func(into, from *group)) []*group {
+		var result []*group
+		index := make(map[string]*group)
+		for _, gr := range groups {
+			k := key(gr)
+			if into, exists := index[k]; exists {
+				combine(into, gr)
+				continue
+			}
+			index[k] = gr
+			result = append(result, gr)
+		}
+		return result
+	}
+
+	var groups []*group
+	for _, t := range tuples {
+		groups = append(groups, &group{tuples: []grantTuple{t}, names: []*ast.Ident{t.name}, roles: []*ast.Ident{t.role}})
+	}
+	groups = merge(groups, func(gr *group) string {
+		return strings.ToLower(gr.tuples[0].object + "\x00" + gr.names[0].Name + "\x00" + gr.roles[0].Name)
+	}, func(into, from *group) {
+		into.tuples = append(into.tuples, from.tuples...)
+	})
+	groups = merge(groups, func(gr *group) string {
+		return signature(gr) + "\x00" + strings.ToLower(gr.names[0].Name)
+	}, func(into, from *group) {
+		into.roles = append(into.roles, from.roles...)
+	})
+	groups = merge(groups, func(gr *group) string {
+		return signature(gr) + "\x00" + strings.ToLower(identsToComparable(gr.roles...))
+	}, func(into, from *group) {
+		into.names = append(into.names, from.names...)
+	})
+
+	grants := make([]*ast.Grant, 0, len(groups))
+	for _, gr := range groups {
+		grants = append(grants, &ast.Grant{Privilege: privilegeFromTuples(gr.tuples, gr.names), Roles: gr.roles})
+	}
+	return grants
+}
+
+// privilegeFromTuples builds the privilege of the tuples on the given objects.
+// All tuples must be on the same kind of object, and only the tuples on the first object and role are used.
+func privilegeFromTuples(tuples []grantTuple, names []*ast.Ident) ast.Privilege {
+	switch tuples[0].object {
+	case "VIEW":
+		return &ast.SelectPrivilegeOnView{Names: names}
+	case "CHANGE STREAM":
+		return &ast.SelectPrivilegeOnChangeStream{Names: names}
+	case "TABLE FUNCTION":
+		return &ast.ExecutePrivilegeOnTableFunction{Names: names}
+	case "ROLE":
+		return &ast.RolePrivilege{Names: names}
+	}
+
+	// A privilege on the whole table and the same privilege on columns are distinct privileges.
+	type entry struct {
+		privilege string
+		columns   []*ast.Ident
+	}
+	var entries []*entry
+	for _, t := range tuples {
+		if t.name != tuples[0].name || t.role != tuples[0].role {
+			continue
+		}
+		found := false
+		for _, e := range entries {
+			if e.privilege == t.privilege && (len(e.columns) == 0) == (t.column == nil) {
+				if t.column != nil {
+					e.columns = append(e.columns, t.column)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			e := &entry{privilege: t.privilege}
+			if t.column != nil {
+				e.columns = []*ast.Ident{t.column}
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	p := &ast.PrivilegeOnTable{Names: names}
+	for _, e := range entries {
+		switch e.privilege {
+		case "SELECT":
+			p.Privileges = append(p.Privileges, &ast.SelectPrivilege{Columns: e.columns})
+		case "INSERT":
+			p.Privileges = append(p.Privileges, &ast.InsertPrivilege{Columns: e.columns})
+		case "UPDATE":
+			p.Privileges = append(p.Privileges, &ast.UpdatePrivilege{Columns: e.columns})
+		case "DELETE":
+			p.Privileges = append(p.Privileges, &ast.DeletePrivilege{})
+		}
+	}
+	return p
+}
+
 // existsGrantResourceIn returns true if any target resource of the grant exists in the given database.
 // Note: this checks resource existence (table/view/change stream…), not whether the grant itself exists.
 func (g *Generator) existsGrantResourceIn(grant *Grant, database *Database) bool {
@@ -1495,9 +3251,19 @@ func (g *Generator) existsGrantResourceIn(grant *Grant, database *Database) bool
 		return false
 
 	case *ast.ExecutePrivilegeOnTableFunction:
-		// Not tracked yet; return true so REVOKE precedes DROP ROLE (safe order).
-		// TODO: Check existence when table functions are modeled.
-		return true
+		for _, name := range p.Names {
+			if _, exists := g.findFunctionByName(database.functions, name.Name); exists {
+				return true
+			}
+			// Functions other than user-defined functions, such as the read functions of change streams, are not tracked.
+			// Return true so REVOKE precedes DROP ROLE (safe order).
+			_, inFrom := g.findFunctionByName(g.from.functions, name.Name)
+			_, inTo := g.findFunctionByName(g.to.functions, name.Name)
+			if !inFrom && !inTo {
+				return true
+			}
+		}
+		return false
 
 	default:
 		return false
@@ -2,9 +2,12 @@ package hammer_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 
 	"github.com/daichirata/hammer/internal/hammer"
 )
@@ -22,6 +25,8 @@ func TestDiff(t *testing.T) {
 		from                string
 		to                  string
 		ignoreAlterDatabase bool
+		rebuildTable        bool
+		convertColumnType   bool
 		expected            []string
 	}{
 		{
@@ -402,6 +407,27 @@ CREATE TABLE t1 (
 				`ALTER TABLE t1 ALTER COLUMN t1_2 SET OPTIONS (allow_commit_timestamp = null)`,
 			},
 		},
+		{
+			name: "change column options",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 TIMESTAMP OPTIONS (allow_commit_timestamp = true),
+  t1_3 STRING(MAX) OPTIONS (locality_group = 'cold'),
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 TIMESTAMP OPTIONS (locality_group = 'cold'),
+  t1_3 STRING(MAX),
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`ALTER TABLE t1 ALTER COLUMN t1_2 SET OPTIONS (locality_group = "cold", allow_commit_timestamp = null)`,
+				`ALTER TABLE t1 ALTER COLUMN t1_3 SET OPTIONS (locality_group = null)`,
+			},
+		},
 		{
 			name: "add hidden column",
 			from: `
@@ -506,6 +532,124 @@ CREATE TABLE t1 (
 				`ALTER TABLE t1 ADD COLUMN t1_2 STRING(1) NOT NULL AS (SUBSTR(t1_1, 1, 1)) STORED`,
 			},
 		},
+		{
+			name: "change generated column expression",
+			from: `
+CREATE TABLE t1 (
+  t1_1 STRING(36) NOT NULL,
+  t1_2 STRING(1) AS (SUBSTR(t1_1, 1, 1)) STORED,
+  t1_3 STRING(MAX),
+  t1_3_Tokens TOKENLIST AS (TOKENIZE_FULLTEXT(t1_3)) HIDDEN,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+CREATE SEARCH INDEX idx_t1_3 ON t1(t1_3_Tokens);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 STRING(36) NOT NULL,
+  t1_2 STRING(1) AS (SUBSTR(t1_1, 2, 1)) STORED,
+  t1_3 STRING(MAX),
+  t1_3_Tokens TOKENLIST AS (TOKENIZE_SUBSTRING(t1_3)) HIDDEN,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+CREATE SEARCH INDEX idx_t1_3 ON t1(t1_3_Tokens);
+`,
+			expected: []string{
+				`DROP INDEX idx_t1_2`,
+				`ALTER TABLE t1 DROP COLUMN t1_2`,
+				`DROP SEARCH INDEX idx_t1_3`,
+				`ALTER TABLE t1 DROP COLUMN t1_3_Tokens`,
+				`ALTER TABLE t1 ADD COLUMN t1_2 STRING(1) AS (SUBSTR(t1_1, 2, 1)) STORED`,
+				`ALTER TABLE t1 ADD COLUMN t1_3_Tokens TOKENLIST AS (TOKENIZE_SUBSTRING(t1_3)) HIDDEN`,
+				`CREATE INDEX idx_t1_2 ON t1(t1_2)`,
+				`CREATE SEARCH INDEX idx_t1_3 ON t1(t1_3_Tokens)`,
+			},
+		},
+		{
+			name: "recreate generated column depending on recreated column",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+  t1_3 INT64 AS (t1_2 * 2) STORED,
+  t1_4 INT64 AS (t1_3 + 1) STORED,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 FLOAT64,
+  t1_3 INT64 AS (t1_2 * 2) STORED,
+  t1_4 INT64 AS (t1_3 + 1) STORED,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`ALTER TABLE t1 DROP COLUMN t1_4`,
+				`ALTER TABLE t1 DROP COLUMN t1_3`,
+				`ALTER TABLE t1 DROP COLUMN t1_2`,
+				`ALTER TABLE t1 ADD COLUMN t1_2 FLOAT64`,
+				`ALTER TABLE t1 ADD COLUMN t1_3 INT64 AS (t1_2 * 2) STORED`,
+				`ALTER TABLE t1 ADD COLUMN t1_4 INT64 AS (t1_3 + 1) STORED`,
+			},
+		},
+		{
+			name: "add generated column depending on new column",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64 NOT NULL AS (t1_3 * 2) STORED,
+  t1_3 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`ALTER TABLE t1 ADD COLUMN t1_3 INT64 NOT NULL DEFAULT (0)`,
+				`ALTER TABLE t1 ALTER COLUMN t1_3 DROP DEFAULT`,
+				`ALTER TABLE t1 ADD COLUMN t1_2 INT64 NOT NULL AS (t1_3 * 2) STORED`,
+			},
+		},
+		{
+			name: "drop generated column before column it depends on",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+  t1_3 INT64 AS (t1_2 * 2) STORED,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`ALTER TABLE t1 DROP COLUMN t1_3`,
+				`ALTER TABLE t1 DROP COLUMN t1_2`,
+			},
+		},
+		{
+			name: "set not null to generated column",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64 AS (t1_1 * 2) STORED,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64 NOT NULL AS (t1_1 * 2) STORED,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`ALTER TABLE t1 DROP COLUMN t1_2`,
+				`ALTER TABLE t1 ADD COLUMN t1_2 INT64 NOT NULL AS (t1_1 * 2) STORED`,
+			},
+		},
 		{
 			name: "change column from generated column to normal",
 			from: `
@@ -787,6 +931,98 @@ CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2, t1_4);
 				`CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2, t1_4)`,
 			},
 		},
+		{
+			name: "alter search index stored columns",
+			from: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+	t1_3 STRING(MAX) NOT NULL,
+	t1_4 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) STORING (t1_3) OPTIONS (sort_order_sharding = true);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+	t1_3 STRING(MAX) NOT NULL,
+	t1_4 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) STORING (t1_4) OPTIONS (sort_order_sharding = true);
+`,
+			expected: []string{
+				`ALTER SEARCH INDEX idx_t1_2 ADD STORED COLUMN t1_4`,
+				`ALTER SEARCH INDEX idx_t1_2 DROP STORED COLUMN t1_3`,
+			},
+		},
+		{
+			name: "change search index options",
+			from: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) OPTIONS (sort_order_sharding = true);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) OPTIONS (disable_automatic_uid_column = true);
+`,
+			expected: []string{
+				`DROP SEARCH INDEX idx_t1_2`,
+				`CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) OPTIONS (disable_automatic_uid_column = true)`,
+			},
+		},
+		{
+			name: "change search index options and stored columns",
+			from: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+	t1_3 STRING(MAX) NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) OPTIONS (sort_order_sharding = true);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+	t1_3 STRING(MAX) NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) STORING (t1_3) OPTIONS (sort_order_sharding = false);
+`,
+			expected: []string{
+				`DROP SEARCH INDEX idx_t1_2`,
+				`CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) STORING (t1_3) OPTIONS (sort_order_sharding = false)`,
+			},
+		},
+		{
+			name: "change search index partitioning",
+			from: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+	t1_3 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) STORING (t1_3);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 STRING(MAX) NOT NULL,
+	t1_2 TOKENLIST AS (TOKENIZE_FULLTEXT(Name)) HIDDEN,
+	t1_3 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) PARTITION BY t1_3;
+`,
+			expected: []string{
+				`DROP SEARCH INDEX idx_t1_2`,
+				`CREATE SEARCH INDEX idx_t1_2 ON t1(t1_2) PARTITION BY t1_3`,
+			},
+		},
 		{
 			name: "drop search index",
 			from: `
@@ -810,6 +1046,103 @@ CREATE TABLE t1 (
 				`DROP SEARCH INDEX idx_t1_2`,
 			},
 		},
+		{
+			name: "add vector index",
+			from: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'COSINE', tree_depth = 2);
+`,
+			expected: []string{
+				`CREATE VECTOR INDEX idx_t1_2 ON t1 (t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = "COSINE", tree_depth = 2)`,
+			},
+		},
+		{
+			name: "alter vector index",
+			from: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'COSINE', tree_depth = 2);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'EUCLIDEAN', tree_depth = 2);
+`,
+			expected: []string{
+				`DROP VECTOR INDEX idx_t1_2`,
+				`CREATE VECTOR INDEX idx_t1_2 ON t1 (t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = "EUCLIDEAN", tree_depth = 2)`,
+			},
+		},
+		{
+			name: "drop vector index",
+			from: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'COSINE', tree_depth = 2);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`DROP VECTOR INDEX idx_t1_2`,
+			},
+		},
+		{
+			name: "change vector indexed column",
+			from: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'COSINE', tree_depth = 2);
+`,
+			to: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT64>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'COSINE', tree_depth = 2);
+`,
+			expected: []string{
+				`DROP VECTOR INDEX idx_t1_2`,
+				`ALTER TABLE t1 DROP COLUMN t1_2`,
+				`ALTER TABLE t1 ADD COLUMN t1_2 ARRAY<FLOAT64>(vector_length => 3)`,
+				`CREATE VECTOR INDEX idx_t1_2 ON t1 (t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = "COSINE", tree_depth = 2)`,
+			},
+		},
+		{
+			name: "drop table with vector index",
+			from: `
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 ARRAY<FLOAT32>(vector_length=>3),
+) PRIMARY KEY(t1_1);
+CREATE VECTOR INDEX idx_t1_2 ON t1(t1_2) WHERE t1_2 IS NOT NULL OPTIONS (distance_type = 'COSINE', tree_depth = 2);
+`,
+			to: ``,
+			expected: []string{
+				`DROP VECTOR INDEX idx_t1_2`,
+				`DROP TABLE t1`,
+			},
+		},
 		{
 			name: "change column (interleaved)",
 			from: `
@@ -878,22 +1211,209 @@ CREATE INDEX idx_t3 ON t3(t3_1);
 			},
 		},
 		{
-			name: "Create table with constraint",
+			name: "change on delete action (interleaved)",
 			from: `
-		`,
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1;
+`,
 			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
 CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
   t2_1 INT64 NOT NULL,
-  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
-) PRIMARY KEY(t2_1);
-		`,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE;
+`,
+			expected: []string{
+				`ALTER TABLE t2 SET ON DELETE CASCADE`,
+			},
+		},
+		{
+			name: "unchanged default on delete action (interleaved)",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE NO ACTION;
+`,
+			expected: []string{},
+		},
+		{
+			name: "change interleave in parent to interleave in",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE;
+CREATE INDEX idx_t2 ON t2(t2_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN t1;
+CREATE INDEX idx_t2 ON t2(t2_1);
+`,
+			expected: []string{
+				`ALTER TABLE t2 SET INTERLEAVE IN t1`,
+			},
+		},
+		{
+			name: "change interleave in to interleave in parent",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN t1;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE;
+`,
+			expected: []string{
+				`ALTER TABLE t2 SET INTERLEAVE IN PARENT t1 ON DELETE CASCADE`,
+			},
+		},
+		{
+			name: "Create table with constraint",
+			from: `
+		`,
+			to: `
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+		`,
+			expected: []string{
+				`CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1)
+) PRIMARY KEY (t2_1)`,
+			},
+		},
+		{
+			name: "create table referencing table defined later",
+			from: `
+		`,
+			to: `
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+		`,
 			expected: []string{
+				`CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL
+) PRIMARY KEY (t1_1)`,
 				`CREATE TABLE t2 (
   t2_1 INT64 NOT NULL,
   CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1)
 ) PRIMARY KEY (t2_1)`,
 			},
 		},
+		{
+			name: "create interleaved table defined before parent",
+			from: `
+		`,
+			to: `
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE;
+
+CREATE INDEX idx_t2 ON t2(t2_1);
+
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+		`,
+			expected: []string{
+				`CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL
+) PRIMARY KEY (t1_1)`,
+				`CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL
+) PRIMARY KEY (t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE`,
+				`CREATE INDEX idx_t2 ON t2(t2_1)`,
+			},
+		},
+		{
+			name: "drop table after dropping foreign key referencing it",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+		`,
+			to: `
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t2_1);
+		`,
+			expected: []string{
+				`ALTER TABLE t2 DROP CONSTRAINT FK_t2_1`,
+				`DROP TABLE t1`,
+			},
+		},
 		{
 			name: "Add named constraint",
 			from: `
@@ -1483,120 +2003,353 @@ ALTER DATABASE db SET OPTIONS(enable_key_visualizer=true);
 			},
 		},
 		{
-			name: "ignore alter database diffs",
+			name: "remove database options while keeping the ones that cannot be reset",
 			from: `
-ALTER DATABASE db SET OPTIONS(optimizer_version=3, version_retention_period='3d');
-CREATE TABLE t1 (
-  t1_1 INT64 NOT NULL,
-) PRIMARY KEY(t1_1);
+ALTER DATABASE db SET OPTIONS(default_leader='us-central1', optimizer_statistics_package='auto_20250101', default_sequence_kind='bit_reversed_positive');
+		`,
+			to: `
+ALTER DATABASE db SET OPTIONS(default_sequence_kind='bit_reversed_positive');
 		`,
+			expected: []string{
+				`ALTER DATABASE db SET OPTIONS (default_sequence_kind = "bit_reversed_positive", default_leader = null, optimizer_statistics_package = null)`,
+			},
+		},
+		{
+			name: "create locality group before table",
+			from: ``,
 			to: `
+CREATE LOCALITY GROUP cold OPTIONS (storage = 'hdd');
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX) OPTIONS (locality_group = 'cold'),
 ) PRIMARY KEY(t1_1);
-			`,
-			ignoreAlterDatabase: true,
-			expected:            []string{},
-		},
-		{
-			name: "drop change stream",
-			from: `
-CREATE CHANGE STREAM SomeStream;
 `,
-			to:                  ``,
-			ignoreAlterDatabase: true,
-			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+			expected: []string{
+				`CREATE LOCALITY GROUP cold OPTIONS (storage = "hdd")`,
+				"CREATE TABLE t1 (\n  t1_1 INT64 NOT NULL,\n  t1_2 STRING(MAX) OPTIONS (locality_group = \"cold\")\n) PRIMARY KEY (t1_1)",
+			},
 		},
 		{
-			name: "drop change stream with watch all",
+			name: "alter locality groups",
 			from: `
-CREATE CHANGE STREAM SomeStream FOR ALL;
+CREATE LOCALITY GROUP cold OPTIONS (storage = 'hdd');
 `,
-			to:                  ``,
-			ignoreAlterDatabase: true,
-			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+			to: `
+ALTER LOCALITY GROUP ` + "`default`" + ` SET OPTIONS (storage = 'ssd', ssd_to_hdd_spill_timespan = '10d');
+CREATE LOCALITY GROUP cold OPTIONS (storage = 'ssd', ssd_to_hdd_spill_timespan = '1d');
+`,
+			expected: []string{
+				"ALTER LOCALITY GROUP `default` SET OPTIONS (storage = \"ssd\", ssd_to_hdd_spill_timespan = \"10d\")",
+				`ALTER LOCALITY GROUP cold SET OPTIONS (storage = "ssd", ssd_to_hdd_spill_timespan = "1d")`,
+			},
 		},
 		{
-			name: "drop change stream with watch tables",
+			name: "move table and column to another locality group",
 			from: `
+ALTER LOCALITY GROUP ` + "`default`" + ` SET OPTIONS (ssd_to_hdd_spill_timespan = '10d');
+CREATE LOCALITY GROUP cold OPTIONS (storage = 'hdd');
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
-) PRIMARY KEY(t1_1);
-CREATE CHANGE STREAM SomeStream FOR t1;
+  t1_2 STRING(MAX) OPTIONS (locality_group = 'cold'),
+) PRIMARY KEY(t1_1), OPTIONS (locality_group = 'cold');
 `,
 			to: `
+CREATE LOCALITY GROUP warm OPTIONS (storage = 'ssd', ssd_to_hdd_spill_timespan = '1d');
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
-) PRIMARY KEY(t1_1);
+  t1_2 STRING(MAX) OPTIONS (locality_group = 'warm'),
+) PRIMARY KEY(t1_1), OPTIONS (locality_group = 'warm');
 `,
-			ignoreAlterDatabase: true,
-			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+			expected: []string{
+				`CREATE LOCALITY GROUP warm OPTIONS (storage = "ssd", ssd_to_hdd_spill_timespan = "1d")`,
+				`ALTER TABLE t1 ALTER COLUMN t1_2 SET OPTIONS (locality_group = "warm")`,
+				`ALTER TABLE t1 SET OPTIONS (locality_group = "warm")`,
+				"ALTER LOCALITY GROUP `default` SET OPTIONS (ssd_to_hdd_spill_timespan = null)",
+				`DROP LOCALITY GROUP cold`,
+			},
 		},
 		{
-			name: "drop change stream with watch columns",
+			name: "drop locality group after table",
 			from: `
+CREATE LOCALITY GROUP cold OPTIONS (storage = 'hdd');
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
-) PRIMARY KEY(t1_1);
-CREATE CHANGE STREAM SomeStream FOR t1(t1_1);
+) PRIMARY KEY(t1_1), OPTIONS (locality_group = 'cold');
 `,
-			to: `
+			to: ``,
+			expected: []string{
+				`DROP TABLE t1`,
+				`DROP LOCALITY GROUP cold`,
+			},
+		},
+		{
+			name: "create function before generated column and view",
+			from: `
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
 ) PRIMARY KEY(t1_1);
 `,
-			ignoreAlterDatabase: true,
-			expected:            []string{"DROP CHANGE STREAM SomeStream"},
-		},
-		{
-			name: "create change stream",
-			from: ``,
 			to: `
-CREATE CHANGE STREAM SomeStream;
+CREATE FUNCTION double_it(x INT64) RETURNS INT64 AS (x * 2);
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64 AS (double_it(t1_1)) STORED,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT double_it(t1.t1_1) AS v FROM t1;
 `,
-			ignoreAlterDatabase: true,
-			expected:            []string{"CREATE CHANGE STREAM SomeStream"},
+			expected: []string{
+				`CREATE FUNCTION double_it(x INT64) RETURNS INT64 AS (x * 2)`,
+				`ALTER TABLE t1 ADD COLUMN t1_2 INT64 AS (double_it(t1_1)) STORED`,
+				`CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT double_it(t1.t1_1) AS v FROM t1`,
+			},
 		},
 		{
-			name: "alter change stream watch none to all",
+			name: "replace function",
 			from: `
-CREATE CHANGE STREAM SomeStream;
+CREATE FUNCTION f1(x INT64) RETURNS INT64 AS (x * 2);
+CREATE FUNCTION f2(x INT64)
+  RETURNS INT64 AS (x * 3);
 `,
 			to: `
-CREATE CHANGE STREAM SomeStream FOR ALL;
+CREATE FUNCTION f1(x INT64) RETURNS INT64 AS (x * 4);
+CREATE OR REPLACE FUNCTION f2(x INT64) RETURNS INT64 AS (x * 3);
 `,
-			ignoreAlterDatabase: true,
-			expected:            []string{"ALTER CHANGE STREAM SomeStream SET FOR ALL"},
+			expected: []string{
+				`CREATE OR REPLACE FUNCTION f1(x INT64) RETURNS INT64 AS (x * 4)`,
+			},
 		},
 		{
-			name: "alter change stream watch none to table",
+			name: "drop function after view",
 			from: `
-CREATE CHANGE STREAM SomeStream;
+CREATE ROLE role1;
+CREATE FUNCTION f1(x INT64) RETURNS INT64 AS (x * 2);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT f1(1) AS v;
+GRANT EXECUTE ON TABLE FUNCTION f1 TO ROLE role1;
 `,
 			to: `
-CREATE TABLE Singers (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE TABLE Albums (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM SomeStream FOR Singers(id), Albums;
+CREATE ROLE role1;
 `,
-			ignoreAlterDatabase: true,
 			expected: []string{
-				`CREATE TABLE Singers (
-  id INT64 NOT NULL
-) PRIMARY KEY (id)`,
-				`CREATE TABLE Albums (
-  id INT64 NOT NULL
-) PRIMARY KEY (id)`,
-				"ALTER CHANGE STREAM SomeStream SET FOR Singers(id), Albums",
+				`DROP VIEW v1`,
+				`DROP FUNCTION f1`,
 			},
 		},
 		{
-			name: "alter change stream watch all to none",
+			name: "drop column after replacing view that stops using it",
 			from: `
-CREATE CHANGE STREAM SomeStream FOR ALL;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1, t1.t1_2 FROM t1;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+`,
+			expected: []string{
+				`CREATE OR REPLACE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1`,
+				`ALTER TABLE t1 DROP COLUMN t1_2`,
+			},
+		},
+		{
+			name: "add column before index and view using it",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1, t1.t1_2 FROM t1;
+`,
+			expected: []string{
+				`ALTER TABLE t1 ADD COLUMN t1_2 INT64`,
+				`CREATE INDEX idx_t1_2 ON t1(t1_2)`,
+				`CREATE OR REPLACE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1, t1.t1_2 FROM t1`,
+			},
+		},
+		{
+			name: "create function after table it reads from",
+			from: ``,
+			to: `
+CREATE FUNCTION count_t1() RETURNS INT64 AS ((SELECT COUNT(*) FROM t1));
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				"CREATE TABLE t1 (\n  t1_1 INT64 NOT NULL\n) PRIMARY KEY (t1_1)",
+				`CREATE FUNCTION count_t1() RETURNS INT64 AS ((SELECT COUNT(*) FROM t1))`,
+			},
+		},
+		{
+			name: "create function after schema qualified table it reads from by quoted names",
+			from: ``,
+			to: `
+CREATE FUNCTION count_t1() RETURNS INT64 AS ((SELECT COUNT(*) FROM ` + "`sch`.`t1`" + `));
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				"CREATE SCHEMA sch",
+				"CREATE TABLE sch.t1 (\n  t1_1 INT64 NOT NULL\n) PRIMARY KEY (t1_1)",
+				"CREATE FUNCTION count_t1() RETURNS INT64 AS ((SELECT COUNT(*) FROM `sch`.`t1`))",
+			},
+		},
+		{
+			name: "create function naming table in string literal and comment before table using it",
+			from: ``,
+			to: `
+CREATE FUNCTION label(x INT64) RETURNS STRING AS (/* not read from t1 */ CONCAT("t1: ", CAST(x AS STRING)));
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX) AS (label(t1_1)) STORED,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				`CREATE FUNCTION label(x INT64) RETURNS STRING AS (/* not read from t1 */ CONCAT("t1: ", CAST(x AS STRING)))`,
+				"CREATE TABLE t1 (\n  t1_1 INT64 NOT NULL,\n  t1_2 STRING(MAX) AS (label(t1_1)) STORED\n) PRIMARY KEY (t1_1)",
+			},
+		},
+		{
+			name: "drop function after replacing view that stops using it",
+			from: `
+CREATE FUNCTION f1(x INT64) RETURNS INT64 AS (x * 2);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT f1(1) AS v;
+`,
+			to: `
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT 1 AS v;
+`,
+			expected: []string{
+				`CREATE OR REPLACE VIEW v1 SQL SECURITY INVOKER AS SELECT 1 AS v`,
+				`DROP FUNCTION f1`,
+			},
+		},
+		{
+			name: "ignore alter database diffs",
+			from: `
+ALTER DATABASE db SET OPTIONS(optimizer_version=3, version_retention_period='3d');
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+		`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+			`,
+			ignoreAlterDatabase: true,
+			expected:            []string{},
+		},
+		{
+			name: "drop change stream",
+			from: `
+CREATE CHANGE STREAM SomeStream;
+`,
+			to:                  ``,
+			ignoreAlterDatabase: true,
+			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+		},
+		{
+			name: "drop change stream with watch all",
+			from: `
+CREATE CHANGE STREAM SomeStream FOR ALL;
+`,
+			to:                  ``,
+			ignoreAlterDatabase: true,
+			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+		},
+		{
+			name: "drop change stream with watch tables",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE CHANGE STREAM SomeStream FOR t1;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			ignoreAlterDatabase: true,
+			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+		},
+		{
+			name: "drop change stream with watch columns",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE CHANGE STREAM SomeStream FOR t1(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			ignoreAlterDatabase: true,
+			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+		},
+		{
+			name: "create change stream",
+			from: ``,
+			to: `
+CREATE CHANGE STREAM SomeStream;
+`,
+			ignoreAlterDatabase: true,
+			expected:            []string{"CREATE CHANGE STREAM SomeStream"},
+		},
+		{
+			name: "alter change stream watch none to all",
+			from: `
+CREATE CHANGE STREAM SomeStream;
+`,
+			to: `
+CREATE CHANGE STREAM SomeStream FOR ALL;
+`,
+			ignoreAlterDatabase: true,
+			expected:            []string{"ALTER CHANGE STREAM SomeStream SET FOR ALL"},
+		},
+		{
+			name: "alter change stream watch none to table",
+			from: `
+CREATE CHANGE STREAM SomeStream;
+`,
+			to: `
+CREATE TABLE Singers (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Albums (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM SomeStream FOR Singers(id), Albums;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				`CREATE TABLE Singers (
+  id INT64 NOT NULL
+) PRIMARY KEY (id)`,
+				`CREATE TABLE Albums (
+  id INT64 NOT NULL
+) PRIMARY KEY (id)`,
+				"ALTER CHANGE STREAM SomeStream SET FOR Singers(id), Albums",
+			},
+		},
+		{
+			name: "alter change stream watch all to none",
+			from: `
+CREATE CHANGE STREAM SomeStream FOR ALL;
 `,
 			to: `
 CREATE CHANGE STREAM SomeStream;
@@ -1892,746 +2645,2311 @@ AS SELECT * FROM t1;
 			},
 		},
 		{
-			name: "table and column names are not case-sensitive",
+			name: "keep SQL SECURITY DEFINER on replace view",
 			from: `
 CREATE TABLE t1 (
 	t1_1 INT64 NOT NULL,
-	t1_2 INT64 NOT NULL,
 ) PRIMARY KEY(t1_1);
+
+CREATE VIEW v1 SQL SECURITY DEFINER AS SELECT * FROM t1 WHERE t1_1 > 0;
 `,
 			to: `
-CREATE TABLE T1 (
+CREATE TABLE t1 (
 	t1_1 INT64 NOT NULL,
-	T1_2 INT64 NOT NULL,
 ) PRIMARY KEY(t1_1);
+
+CREATE VIEW v1 SQL SECURITY DEFINER AS SELECT * FROM t1;
 `,
-			expected: []string{},
+			expected: []string{
+				`CREATE OR REPLACE VIEW v1 SQL SECURITY DEFINER AS SELECT * FROM t1`,
+			},
 		},
 		{
-			name: "named schema",
+			name: "change view security type",
 			from: `
-			CREATE TABLE schema.t1 (
-				t1_1 INT64 NOT NULL,
-			) PRIMARY KEY(t1_1);
-			CREATE INDEX schema.idx_t1_1 ON schema.t1(t1_1);
-			`,
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT 1 AS c;
+`,
 			to: `
-			CREATE TABLE schema.t1 (
-				t1_1 INT64 NOT NULL,
-				t1_2 INT64,
-			) PRIMARY KEY(t1_1);
-			`,
+CREATE VIEW v1 SQL SECURITY DEFINER AS SELECT 1 AS c;
+`,
 			expected: []string{
-				"DROP INDEX schema.idx_t1_1",
-				"ALTER TABLE schema.t1 ADD COLUMN t1_2 INT64",
+				`CREATE OR REPLACE VIEW v1 SQL SECURITY DEFINER AS SELECT 1 AS c`,
 			},
 		},
 		{
-			name: "keyword identifier",
+			name: "unchanged view is not replaced",
 			from: `
-			CREATE TABLE ` + "`Order`" + ` (
-				order_1 INT64 NOT NULL,
-			) PRIMARY KEY(order_1);
-			`,
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT * FROM t1;
+`,
 			to: `
-			CREATE TABLE ` + "`Order`" + ` (
-				order_1 INT64 NOT NULL,
-				order_2 INT64,
-			) PRIMARY KEY(order_1);
-			`,
-			expected: []string{
-				"ALTER TABLE `Order` ADD COLUMN order_2 INT64",
-			},
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+
+create view v1
+sql security invoker
+as select * from T1;
+`,
+			expected: []string{},
 		},
 		{
-			name: "create role",
+			name: "create views in dependency order",
 			from: `
-			CREATE ROLE role1;
-			`,
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
 			to: `
-			CREATE ROLE role1;
-			CREATE ROLE role2;
-			`,
+CREATE VIEW v2 SQL SECURITY INVOKER AS SELECT v1.t1_1 FROM v1;
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
 			expected: []string{
-				`CREATE ROLE role2`,
+				`CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1`,
+				`CREATE VIEW v2 SQL SECURITY INVOKER AS SELECT v1.t1_1 FROM v1`,
 			},
 		},
 		{
-			name: "drop role",
+			name: "drop views in reverse dependency order before dropping table",
 			from: `
-			CREATE ROLE role1;
-			CREATE ROLE role2;
-			`,
-			to: `
-			CREATE ROLE role1;
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+CREATE VIEW v2 SQL SECURITY INVOKER AS SELECT v1.t1_1 FROM v1;
 `,
+			to: ``,
 			expected: []string{
-				`DROP ROLE role2`,
+				`DROP VIEW v2`,
+				`DROP VIEW v1`,
+				`DROP TABLE t1`,
 			},
 		},
 		{
-			name: "drop unused role only",
+			name: "recreate view when recreating table it reads from",
 			from: `
-			CREATE ROLE role1;
-			CREATE ROLE role2;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+GRANT SELECT ON VIEW v1 TO ROLE role1;
+`,
 			to: `
-			CREATE ROLE role1;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 INT64 NOT NULL,
+) PRIMARY KEY(t1_2);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+GRANT SELECT ON VIEW v1 TO ROLE role1;
+`,
 			expected: []string{
-				`DROP ROLE role2`,
+				`DROP VIEW v1`,
+				`DROP TABLE t1`,
+				"CREATE TABLE t1 (\n  t1_1 INT64 NOT NULL,\n  t1_2 INT64 NOT NULL\n) PRIMARY KEY (t1_2)",
+				`CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1`,
+				`GRANT SELECT ON VIEW v1 TO ROLE role1`,
 			},
 		},
 		{
-			name: "grant role",
-			from: `
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+			name: "create model",
+			from: ``,
 			to: `
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			GRANT SELECT ON TABLE T2 TO ROLE role2;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (
+  endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m'
+);
+`,
 			expected: []string{
-				`GRANT SELECT ON TABLE T2 TO ROLE role2`,
+				`CREATE MODEL m1 INPUT (prompt STRING(MAX)) OUTPUT (content STRING(MAX)) REMOTE OPTIONS (endpoint = "//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m")`,
 			},
 		},
 		{
-			name: "grant select on view",
+			name: "drop model",
 			from: `
-				CREATE ROLE role1;
-			`,
-			to: `
-				CREATE ROLE role1;
-				GRANT SELECT ON VIEW V1 TO ROLE role1;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (
+  endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m'
+);
+`,
+			to: ``,
 			expected: []string{
-				`GRANT SELECT ON VIEW V1 TO ROLE role1`,
+				`DROP MODEL m1`,
 			},
 		},
 		{
-			name: "grant select on change stream",
+			name: "no diff model",
 			from: `
-				CREATE ROLE role1;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+`,
 			to: `
-				CREATE ROLE role1;
-				GRANT SELECT ON CHANGE STREAM cs1 TO ROLE role1;
-			`,
-			expected: []string{
-				`GRANT SELECT ON CHANGE STREAM cs1 TO ROLE role1`,
-			},
+CREATE MODEL m1
+INPUT (prompt STRING(MAX),)
+OUTPUT (content STRING(MAX),)
+REMOTE
+OPTIONS (
+  endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m'
+);
+`,
+			expected: []string{},
 		},
 		{
-			name: "grant execute on table function",
+			name: "replace model when output columns changed",
 			from: `
-				CREATE ROLE role1;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+`,
 			to: `
-				CREATE ROLE role1;
-				GRANT EXECUTE ON TABLE FUNCTION tf1 TO ROLE role1;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX), score FLOAT64)
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+`,
 			expected: []string{
-				`GRANT EXECUTE ON TABLE FUNCTION tf1 TO ROLE role1`,
+				`CREATE OR REPLACE MODEL m1 INPUT (prompt STRING(MAX)) OUTPUT (content STRING(MAX), score FLOAT64) REMOTE OPTIONS (endpoint = "//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m")`,
 			},
 		},
 		{
-			name: "grant role with same roles in different order",
+			name: "alter model options",
 			from: `
-			GRANT SELECT ON TABLE T1 TO ROLE role1, role2;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m', default_batch_size = 1);
+`,
 			to: `
-			GRANT SELECT ON TABLE T1 TO ROLE role2, role1;
-			`,
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m2');
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T1 FROM ROLE role1, role2`,
-				`GRANT SELECT ON TABLE T1 TO ROLE role2, role1`,
+				`ALTER MODEL m1 SET OPTIONS (endpoint = "//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m2", default_batch_size = null)`,
 			},
 		},
 		{
-			name: "revoke role",
+			name: "create sequence",
 			from: `
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			GRANT SELECT ON TABLE T2 TO ROLE role2;
-			`,
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+`,
 			to: `
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE SEQUENCE seq OPTIONS (sequence_kind = 'bit_reversed_positive');
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  seq_id INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq)),
+) PRIMARY KEY(id);
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T2 FROM ROLE role2`,
+				`CREATE SEQUENCE seq OPTIONS (sequence_kind = "bit_reversed_positive")`,
+				`ALTER TABLE t1 ADD COLUMN seq_id INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq))`,
 			},
 		},
 		{
-			name: "replace grant role",
+			name: "alter sequence options",
 			from: `
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE SEQUENCE seq OPTIONS (sequence_kind = 'bit_reversed_positive', skip_range_min = 1, skip_range_max = 1000, start_with_counter = 10);
+`,
 			to: `
-			GRANT SELECT, INSERT ON TABLE T1, T2 TO ROLE role1, role2;
-			`,
+CREATE SEQUENCE seq OPTIONS (sequence_kind = 'bit_reversed_positive', skip_range_min = 1, skip_range_max = 5000);
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
-				`GRANT SELECT, INSERT ON TABLE T1, T2 TO ROLE role1, role2`,
+				`ALTER SEQUENCE seq SET OPTIONS (skip_range_max = 5000, start_with_counter = null)`,
 			},
 		},
 		{
-			name: "revoke only, keep role",
+			name: "sequence clauses are compared with options",
 			from: `
-			CREATE ROLE role1;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE SEQUENCE seq BIT_REVERSED_POSITIVE SKIP RANGE 1, 1000 OPTIONS (start_with_counter = 1);
+`,
 			to: `
-			CREATE ROLE role1;
-			`,
+CREATE SEQUENCE seq OPTIONS (sequence_kind = 'bit_reversed_positive', skip_range_min = 1, skip_range_max = 1000, start_with_counter = 10);
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
+				`ALTER SEQUENCE seq SET OPTIONS (start_with_counter = 10)`,
 			},
 		},
 		{
-			name: "grant same role on different tables",
+			name: "drop sequence after column default is removed",
 			from: `
-			CREATE ROLE role1;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE SEQUENCE seq OPTIONS (sequence_kind = 'bit_reversed_positive');
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  seq_id INT64 NOT NULL DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq)),
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq)),
+) PRIMARY KEY(id);
+`,
 			to: `
-			CREATE ROLE role1;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			GRANT SELECT ON TABLE T2 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  seq_id INT64 NOT NULL,
+) PRIMARY KEY(id);
+`,
 			expected: []string{
-				`GRANT SELECT ON TABLE T2 TO ROLE role1`,
+				`ALTER TABLE t1 ALTER COLUMN seq_id INT64 NOT NULL`,
+				`DROP TABLE t2`,
+				`DROP SEQUENCE seq`,
 			},
 		},
 		{
-			name: "revoke same role on different tables",
+			name: "create model before the view using it",
 			from: `
-			CREATE ROLE role1;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			GRANT SELECT ON TABLE T2 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  prompt STRING(MAX),
+) PRIMARY KEY(id);
+`,
 			to: `
-			CREATE ROLE role1;
-			GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  prompt STRING(MAX),
+) PRIMARY KEY(id);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT content FROM ML.PREDICT(MODEL m1, TABLE t1);
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T2 FROM ROLE role1`,
+				`CREATE MODEL m1 INPUT (prompt STRING(MAX)) OUTPUT (content STRING(MAX)) REMOTE OPTIONS (endpoint = "//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m")`,
+				`CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT content FROM ML.PREDICT(MODEL m1, TABLE t1)`,
 			},
 		},
 		{
-			name: "grant on multiple tables in different order",
+			name: "drop model after the view using it",
 			from: `
-				GRANT SELECT ON TABLE T1, T2 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  prompt STRING(MAX),
+) PRIMARY KEY(id);
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT content FROM ML.PREDICT(MODEL m1, TABLE t1);
+`,
 			to: `
-				GRANT SELECT ON TABLE T2, T1 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  prompt STRING(MAX),
+) PRIMARY KEY(id);
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T1, T2 FROM ROLE role1`,
-				`GRANT SELECT ON TABLE T2, T1 TO ROLE role1`,
+				`DROP VIEW v1`,
+				`DROP MODEL m1`,
 			},
 		},
 		{
-			name: "replace privilege type on same table",
+			name: "create property graph",
 			from: `
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+`,
 			to: `
-				GRANT INSERT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person)
+  EDGE TABLES (
+    Knows
+      SOURCE KEY (id) REFERENCES Person (id)
+      DESTINATION KEY (dst_id) REFERENCES Person (id)
+  );
+`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
-				`GRANT INSERT ON TABLE T1 TO ROLE role1`,
+				`CREATE PROPERTY GRAPH FinGraph NODE TABLES (Person) EDGE TABLES (Knows SOURCE KEY (id) REFERENCES Person (id) DESTINATION KEY (dst_id) REFERENCES Person (id))`,
 			},
 		},
 		{
-			name: "grant multiple privileges at once",
+			name: "create property graph after its tables",
 			from: ``,
-			to: `
-				GRANT SELECT, INSERT, DELETE ON TABLE T1 TO ROLE role1;
-			`,
+			to: `CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person)
+  EDGE TABLES (
+    Knows
+      SOURCE KEY (id) REFERENCES Person (id)
+      DESTINATION KEY (dst_id) REFERENCES Person (id)
+  );
+
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+`,
 			expected: []string{
-				`GRANT SELECT, INSERT, DELETE ON TABLE T1 TO ROLE role1`,
+				"CREATE TABLE Person (\n  id INT64 NOT NULL\n) PRIMARY KEY (id)",
+				"CREATE TABLE Knows (\n  id INT64 NOT NULL,\n  dst_id INT64 NOT NULL\n) PRIMARY KEY (id, dst_id),\n  INTERLEAVE IN PARENT Person",
+				`CREATE PROPERTY GRAPH FinGraph NODE TABLES (Person) EDGE TABLES (Knows SOURCE KEY (id) REFERENCES Person (id) DESTINATION KEY (dst_id) REFERENCES Person (id))`,
 			},
 		},
 		{
-			name: "revoke select column and grant new columns",
+			name: "replace property graph",
 			from: `
-				GRANT SELECT(col1) ON TABLE T1 TO ROLE role1;
-			`,
-			to: `
-				GRANT SELECT(col1, col2) ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person)
+  EDGE TABLES (
+    Knows
+      SOURCE KEY (id) REFERENCES Person (id)
+      DESTINATION KEY (dst_id) REFERENCES Person (id)
+  );
+`,
+			to: `
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person);
+`,
 			expected: []string{
-				`REVOKE SELECT(col1) ON TABLE T1 FROM ROLE role1`,
-				`GRANT SELECT(col1, col2) ON TABLE T1 TO ROLE role1`,
+				`CREATE OR REPLACE PROPERTY GRAPH FinGraph NODE TABLES (Person)`,
 			},
 		},
 		{
-			name: "revoke on table before DROP TABLE",
+			name: "drop property graph before its tables",
 			from: `
-				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64);
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
-			to: `
-				CREATE ROLE role1;
-			`,
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person)
+  EDGE TABLES (
+    Knows
+      SOURCE KEY (id) REFERENCES Person (id)
+      DESTINATION KEY (dst_id) REFERENCES Person (id)
+  );
+`,
+			to: ``,
 			expected: []string{
-				`DROP TABLE T1`,
+				`DROP PROPERTY GRAPH FinGraph`,
+				`DROP TABLE Knows`,
+				`DROP TABLE Person`,
 			},
 		},
 		{
-			name: "revoke on table before DROP TABLE (PK change triggers drop)",
+			name: "drop and create property graph when recreating its table",
 			from: `
-				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64,name STRING(100)) PRIMARY KEY(id);
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
-			to: `
-				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64,name STRING(100)) PRIMARY KEY(name);
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id), INTERLEAVE IN PARENT Person;
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person)
+  EDGE TABLES (
+    Knows
+      SOURCE KEY (id) REFERENCES Person (id)
+      DESTINATION KEY (dst_id) REFERENCES Person (id)
+  );
+`,
+			to: `
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Knows (
+  id INT64 NOT NULL,
+  dst_id INT64 NOT NULL,
+) PRIMARY KEY(id, dst_id);
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person)
+  EDGE TABLES (
+    Knows
+      SOURCE KEY (id) REFERENCES Person (id)
+      DESTINATION KEY (dst_id) REFERENCES Person (id)
+  );
+`,
 			expected: []string{
-				`DROP TABLE T1`,
-				"CREATE TABLE T1 (\n  id INT64,\n  name STRING(100)\n) PRIMARY KEY (name)",
-				`GRANT SELECT ON TABLE T1 TO ROLE role1`,
+				`DROP PROPERTY GRAPH FinGraph`,
+				`DROP TABLE Knows`,
+				"CREATE TABLE Knows (\n  id INT64 NOT NULL,\n  dst_id INT64 NOT NULL\n) PRIMARY KEY (id, dst_id)",
+				`CREATE PROPERTY GRAPH FinGraph NODE TABLES (Person) EDGE TABLES (Knows SOURCE KEY (id) REFERENCES Person (id) DESTINATION KEY (dst_id) REFERENCES Person (id))`,
 			},
 		},
 		{
-			name: "revoke on table before DROP TABLE (interleave parent changed)",
+			name: "table and column names are not case-sensitive",
 			from: `
-				CREATE ROLE role1;
-				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
-				CREATE TABLE P2 (pid INT64 NOT NULL) PRIMARY KEY(pid);
-				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id), INTERLEAVE IN PARENT P1;
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
+CREATE TABLE t1 (
+	t1_1 INT64 NOT NULL,
+	t1_2 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
 			to: `
-				CREATE ROLE role1;
-				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
-				CREATE TABLE P2 (pid INT64 NOT NULL) PRIMARY KEY(pid);
-				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id), INTERLEAVE IN PARENT P2;
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-			`,
-			expected: []string{
-				`DROP TABLE T1`,
-				"CREATE TABLE T1 (\n  id INT64 NOT NULL\n) PRIMARY KEY (id),\n  INTERLEAVE IN PARENT P2",
-				`GRANT SELECT ON TABLE T1 TO ROLE role1`,
-			},
+CREATE TABLE T1 (
+	t1_1 INT64 NOT NULL,
+	T1_2 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{},
 		},
 		{
-			name: "revoke on table before DROP TABLE (removing interleave)",
+			name: "named schema",
 			from: `
-				CREATE ROLE role1;
-				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
-				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id), INTERLEAVE IN PARENT P1;
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			CREATE INDEX schema.idx_t1_1 ON schema.t1(t1_1);
 			`,
 			to: `
-				CREATE ROLE role1;
-				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
-				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id);
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+				t1_2 INT64,
+			) PRIMARY KEY(t1_1);
 			`,
 			expected: []string{
-				`DROP TABLE T1`,
-				"CREATE TABLE T1 (\n  id INT64 NOT NULL\n) PRIMARY KEY (id)",
-				`GRANT SELECT ON TABLE T1 TO ROLE role1`,
+				"DROP INDEX schema.idx_t1_1",
+				"ALTER TABLE schema.t1 ADD COLUMN t1_2 INT64",
 			},
 		},
 		{
-			name: "revoke on column before DROP COLUMN",
+			name: "create named schema",
+			from: ``,
+			to: `
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			CREATE SCHEMA schema;
+			CREATE INDEX schema.idx_t1_1 ON schema.t1(t1_1);
+			`,
+			expected: []string{
+				"CREATE SCHEMA schema",
+				"CREATE TABLE schema.t1 (\n  t1_1 INT64 NOT NULL\n) PRIMARY KEY (t1_1)",
+				"CREATE INDEX schema.idx_t1_1 ON schema.t1(t1_1)",
+			},
+		},
+		{
+			name: "drop named schema",
 			from: `
-				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64, name STRING(100));
-				GRANT SELECT(name) ON TABLE T1 TO ROLE role1;
+			CREATE SCHEMA schema;
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			CREATE VIEW schema.v1 SQL SECURITY INVOKER AS SELECT t1_1 FROM schema.t1;
+			`,
+			to: ``,
+			expected: []string{
+				"DROP VIEW schema.v1",
+				"DROP TABLE schema.t1",
+				"DROP SCHEMA schema",
+			},
+		},
+		{
+			name: "named schema qualified names are not case-sensitive",
+			from: `
+			CREATE SCHEMA schema;
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			CREATE INDEX schema.idx_t1_1 ON schema.t1(t1_1);
 			`,
 			to: `
-				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64);
+			CREATE SCHEMA SCHEMA;
+			CREATE TABLE SCHEMA.T1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			CREATE INDEX SCHEMA.IDX_T1_1 ON SCHEMA.T1(t1_1);
+			`,
+			expected: []string{},
+		},
+		{
+			name: "index on table named in different case",
+			from: `
+			CREATE SCHEMA schema;
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			`,
+			to: `
+			CREATE SCHEMA schema;
+			CREATE TABLE schema.t1 (
+				t1_1 INT64 NOT NULL,
+			) PRIMARY KEY(t1_1);
+			CREATE INDEX schema.idx_t1_1 ON SCHEMA.T1(t1_1);
+			ALTER TABLE SCHEMA.T1 ADD COLUMN t1_2 INT64;
 			`,
 			expected: []string{
-				`ALTER TABLE T1 DROP COLUMN name`,
+				"ALTER TABLE schema.t1 ADD COLUMN t1_2 INT64",
+				"CREATE INDEX schema.idx_t1_1 ON SCHEMA.T1(t1_1)",
 			},
 		},
 		{
-			name: "revoke on view before DROP VIEW",
+			name: "keyword identifier",
+			from: `
+			CREATE TABLE ` + "`Order`" + ` (
+				order_1 INT64 NOT NULL,
+			) PRIMARY KEY(order_1);
+			`,
+			to: `
+			CREATE TABLE ` + "`Order`" + ` (
+				order_1 INT64 NOT NULL,
+				order_2 INT64,
+			) PRIMARY KEY(order_1);
+			`,
+			expected: []string{
+				"ALTER TABLE `Order` ADD COLUMN order_2 INT64",
+			},
+		},
+		{
+			name: "create role",
+			from: `
+			CREATE ROLE role1;
+			`,
+			to: `
+			CREATE ROLE role1;
+			CREATE ROLE role2;
+			`,
+			expected: []string{
+				`CREATE ROLE role2`,
+			},
+		},
+		{
+			name: "drop role",
+			from: `
+			CREATE ROLE role1;
+			CREATE ROLE role2;
+			`,
+			to: `
+			CREATE ROLE role1;
+`,
+			expected: []string{
+				`DROP ROLE role2`,
+			},
+		},
+		{
+			name: "drop unused role only",
+			from: `
+			CREATE ROLE role1;
+			CREATE ROLE role2;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+			CREATE ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`DROP ROLE role2`,
+			},
+		},
+		{
+			name: "grant role",
+			from: `
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			GRANT SELECT ON TABLE T2 TO ROLE role2;
+			`,
+			expected: []string{
+				`GRANT SELECT ON TABLE T2 TO ROLE role2`,
+			},
+		},
+		{
+			name: "grant select on view",
 			from: `
 				CREATE ROLE role1;
-				CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1;
-				GRANT SELECT ON VIEW V1 TO ROLE role1;
 			`,
 			to: `
 				CREATE ROLE role1;
+				GRANT SELECT ON VIEW V1 TO ROLE role1;
 			`,
 			expected: []string{
-				`DROP VIEW V1`,
+				`GRANT SELECT ON VIEW V1 TO ROLE role1`,
 			},
 		},
 		{
-			name: "revoke on change stream before DROP CHANGE STREAM",
+			name: "grant select on change stream",
 			from: `
 				CREATE ROLE role1;
-				CREATE CHANGE STREAM CS1 FOR ALL;
-				GRANT SELECT ON CHANGE STREAM CS1 TO ROLE role1;
 			`,
 			to: `
 				CREATE ROLE role1;
+				GRANT SELECT ON CHANGE STREAM cs1 TO ROLE role1;
 			`,
 			expected: []string{
-				`DROP CHANGE STREAM CS1`,
+				`GRANT SELECT ON CHANGE STREAM cs1 TO ROLE role1`,
 			},
 		},
 		{
-			name: "revoke table grant before DROP ROLE",
+			name: "grant execute on table function",
 			from: `
 				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64);
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
 			`,
 			to: `
-				CREATE TABLE T1 (id INT64);
+				CREATE ROLE role1;
+				GRANT EXECUTE ON TABLE FUNCTION tf1 TO ROLE role1;
 			`,
 			expected: []string{
-				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
-				`DROP ROLE role1`,
+				`GRANT EXECUTE ON TABLE FUNCTION tf1 TO ROLE role1`,
 			},
 		},
 		{
-			name: "revoke view grant before DROP ROLE",
+			name: "grant role with same roles in different order",
 			from: `
-				CREATE ROLE role1;
-				CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1;
-				GRANT SELECT ON VIEW V1 TO ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1, role2;
 			`,
 			to: `
-				CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1;
+			GRANT SELECT ON TABLE T1 TO ROLE role2, role1;
+			`,
+			expected: []string{},
+		},
+		{
+			name: "revoke role",
+			from: `
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			GRANT SELECT ON TABLE T2 TO ROLE role2;
+			`,
+			to: `
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
 			`,
 			expected: []string{
-				`CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT 1`,
-				`REVOKE SELECT ON VIEW V1 FROM ROLE role1`,
-				`DROP ROLE role1`,
+				`REVOKE SELECT ON TABLE T2 FROM ROLE role2`,
 			},
 		},
 		{
-			name: "revoke change stream grant before DROP ROLE",
+			name: "replace grant role",
 			from: `
-				CREATE ROLE role1;
-				CREATE CHANGE STREAM CS1 FOR ALL;
-				GRANT SELECT ON CHANGE STREAM CS1 TO ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
 			`,
 			to: `
-				CREATE CHANGE STREAM CS1 FOR ALL;
+			GRANT SELECT, INSERT ON TABLE T1, T2 TO ROLE role1, role2;
 			`,
 			expected: []string{
-				`REVOKE SELECT ON CHANGE STREAM CS1 FROM ROLE role1`,
-				`DROP ROLE role1`,
+				`GRANT SELECT, INSERT ON TABLE T1 TO ROLE role2`,
+				`GRANT SELECT, INSERT ON TABLE T2 TO ROLE role1, role2`,
+				`GRANT INSERT ON TABLE T1 TO ROLE role1`,
 			},
 		},
 		{
-			name: "drop role: revoke only for resources that remain in target",
+			name: "revoke only, keep role",
 			from: `
-				CREATE ROLE role1;
-				CREATE TABLE T1 (id INT64);
-				CREATE TABLE T2 (id INT64);
-				GRANT SELECT ON TABLE T1 TO ROLE role1;
-				GRANT SELECT ON TABLE T2 TO ROLE role1;
+			CREATE ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
 			`,
 			to: `
-				CREATE TABLE T1 (id INT64);
+			CREATE ROLE role1;
 			`,
 			expected: []string{
-				`DROP TABLE T2`,
 				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
-				`DROP ROLE role1`,
 			},
 		},
 		{
-			name: "recreating table and change stream watches multiple tables",
+			name: "grant same role on different tables",
 			from: `
-				CREATE TABLE T1 (id INT64, name STRING(100)) PRIMARY KEY(id, name);
-				CREATE TABLE T2 (id INT64 PRIMARY KEY);
-				CREATE CHANGE STREAM CS1 FOR T1, T2;
+			CREATE ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
 			`,
 			to: `
-				CREATE TABLE T1 (id INT64, name STRING(100) NOT NULL) PRIMARY KEY(id, name);
-				CREATE TABLE T2 (id INT64 PRIMARY KEY);
-				CREATE CHANGE STREAM CS1 FOR T1, T2;
+			CREATE ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			GRANT SELECT ON TABLE T2 TO ROLE role1;
 			`,
 			expected: []string{
-				`ALTER CHANGE STREAM CS1 SET FOR T2`,
-				`DROP TABLE T1`,
-				"CREATE TABLE T1 (\n  id INT64,\n  name STRING(100) NOT NULL\n) PRIMARY KEY (id, name)",
-				`ALTER CHANGE STREAM CS1 SET FOR T1, T2`,
+				`GRANT SELECT ON TABLE T2 TO ROLE role1`,
 			},
 		},
 		{
-			name: "recreating table and only change stream is for that table",
+			name: "revoke same role on different tables",
 			from: `
-				CREATE TABLE T1 (id INT64, name STRING(100)) PRIMARY KEY(id, name);
-				CREATE CHANGE STREAM CS1 FOR T1;
+			CREATE ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
+			GRANT SELECT ON TABLE T2 TO ROLE role1;
 			`,
 			to: `
-				CREATE TABLE T1 (id INT64, name STRING(100) NOT NULL) PRIMARY KEY(id, name);
-				CREATE CHANGE STREAM CS1 FOR T1;
+			CREATE ROLE role1;
+			GRANT SELECT ON TABLE T1 TO ROLE role1;
 			`,
 			expected: []string{
-				`DROP CHANGE STREAM CS1`,
-				`DROP TABLE T1`,
-				"CREATE TABLE T1 (\n  id INT64,\n  name STRING(100) NOT NULL\n) PRIMARY KEY (id, name)",
-				`CREATE CHANGE STREAM CS1 FOR T1`,
+				`REVOKE SELECT ON TABLE T2 FROM ROLE role1`,
 			},
 		},
 		{
-			name: "drop change stream referenced by multiple tables",
+			name: "grant on multiple tables in different order",
+			from: `
+				GRANT SELECT ON TABLE T1, T2 TO ROLE role1;
+			`,
+			to: `
+				GRANT SELECT ON TABLE T2, T1 TO ROLE role1;
+			`,
+			expected: []string{},
+		},
+		{
+			name: "replace privilege type on same table",
 			from: `
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				GRANT INSERT ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
+				`GRANT INSERT ON TABLE T1 TO ROLE role1`,
+			},
+		},
+		{
+			name: "grant multiple privileges at once",
+			from: ``,
+			to: `
+				GRANT SELECT, INSERT, DELETE ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`GRANT SELECT, INSERT, DELETE ON TABLE T1 TO ROLE role1`,
+			},
+		},
+		{
+			name: "grant new select columns",
+			from: `
+				GRANT SELECT(col1) ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				GRANT SELECT(col1, col2) ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`GRANT SELECT(col2) ON TABLE T1 TO ROLE role1`,
+			},
+		},
+		{
+			name: "revoke select columns",
+			from: `
+				GRANT SELECT(col1, col2, col3) ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				GRANT SELECT(col1, col2) ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`REVOKE SELECT(col3) ON TABLE T1 FROM ROLE role1`,
+			},
+		},
+		{
+			name: "revoke privileges from some roles",
+			from: `
+				GRANT SELECT, UPDATE(col1, col2) ON TABLE T1, T2 TO ROLE role1, role2;
+			`,
+			to: `
+				GRANT SELECT ON TABLE T1, T2 TO ROLE role1, role2;
+				GRANT UPDATE(col1, col2) ON TABLE T1, T2 TO ROLE role1;
+				GRANT INSERT ON TABLE T1 TO ROLE role2;
+			`,
+			expected: []string{
+				`REVOKE UPDATE(col1, col2) ON TABLE T1, T2 FROM ROLE role2`,
+				`GRANT INSERT ON TABLE T1 TO ROLE role2`,
+			},
+		},
+		{
+			name: "revoke on table before DROP TABLE",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64);
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+			`,
+			expected: []string{
+				`DROP TABLE T1`,
+			},
+		},
+		{
+			name: "revoke on table before DROP TABLE (PK change triggers drop)",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64,name STRING(100)) PRIMARY KEY(id);
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64,name STRING(100)) PRIMARY KEY(name);
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`DROP TABLE T1`,
+				"CREATE TABLE T1 (\n  id INT64,\n  name STRING(100)\n) PRIMARY KEY (name)",
+				`GRANT SELECT ON TABLE T1 TO ROLE role1`,
+			},
+		},
+		{
+			name: "revoke on table before DROP TABLE (interleave parent changed)",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
+				CREATE TABLE P2 (pid INT64 NOT NULL) PRIMARY KEY(pid);
+				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id), INTERLEAVE IN PARENT P1;
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
+				CREATE TABLE P2 (pid INT64 NOT NULL) PRIMARY KEY(pid);
+				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id), INTERLEAVE IN PARENT P2;
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`DROP TABLE T1`,
+				"CREATE TABLE T1 (\n  id INT64 NOT NULL\n) PRIMARY KEY (id),\n  INTERLEAVE IN PARENT P2",
+				`GRANT SELECT ON TABLE T1 TO ROLE role1`,
+			},
+		},
+		{
+			name: "revoke on table before DROP TABLE (removing interleave)",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
+				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id), INTERLEAVE IN PARENT P1;
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+				CREATE TABLE P1 (pid INT64 NOT NULL) PRIMARY KEY(pid);
+				CREATE TABLE T1 (id INT64 NOT NULL) PRIMARY KEY(id);
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			expected: []string{
+				`DROP TABLE T1`,
+				"CREATE TABLE T1 (\n  id INT64 NOT NULL\n) PRIMARY KEY (id)",
+				`GRANT SELECT ON TABLE T1 TO ROLE role1`,
+			},
+		},
+		{
+			name: "revoke on column before DROP COLUMN",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64, name STRING(100));
+				GRANT SELECT(name) ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64);
+			`,
+			expected: []string{
+				`ALTER TABLE T1 DROP COLUMN name`,
+			},
+		},
+		{
+			name: "revoke on view before DROP VIEW",
+			from: `
+				CREATE ROLE role1;
+				CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1;
+				GRANT SELECT ON VIEW V1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+			`,
+			expected: []string{
+				`DROP VIEW V1`,
+			},
+		},
+		{
+			name: "revoke on change stream before DROP CHANGE STREAM",
+			from: `
+				CREATE ROLE role1;
+				CREATE CHANGE STREAM CS1 FOR ALL;
+				GRANT SELECT ON CHANGE STREAM CS1 TO ROLE role1;
+			`,
+			to: `
+				CREATE ROLE role1;
+			`,
+			expected: []string{
+				`DROP CHANGE STREAM CS1`,
+			},
+		},
+		{
+			name: "revoke table grant before DROP ROLE",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64);
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+			`,
+			to: `
+				CREATE TABLE T1 (id INT64);
+			`,
+			expected: []string{
+				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
+				`DROP ROLE role1`,
+			},
+		},
+		{
+			name: "revoke view grant before DROP ROLE",
+			from: `
+				CREATE ROLE role1;
+				CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1;
+				GRANT SELECT ON VIEW V1 TO ROLE role1;
+			`,
+			to: `
+				CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1;
+			`,
+			expected: []string{
+				`REVOKE SELECT ON VIEW V1 FROM ROLE role1`,
+				`DROP ROLE role1`,
+			},
+		},
+		{
+			name: "revoke change stream grant before DROP ROLE",
+			from: `
+				CREATE ROLE role1;
+				CREATE CHANGE STREAM CS1 FOR ALL;
+				GRANT SELECT ON CHANGE STREAM CS1 TO ROLE role1;
+			`,
+			to: `
+				CREATE CHANGE STREAM CS1 FOR ALL;
+			`,
+			expected: []string{
+				`REVOKE SELECT ON CHANGE STREAM CS1 FROM ROLE role1`,
+				`DROP ROLE role1`,
+			},
+		},
+		{
+			name: "revoke role membership before DROP ROLE",
+			from: `
+			CREATE ROLE parent;
+			CREATE ROLE child;
+			GRANT ROLE parent TO ROLE child;
+			`,
+			to: `
+			CREATE ROLE child;
+			`,
+			expected: []string{
+				`REVOKE ROLE parent FROM ROLE child`,
+				`DROP ROLE parent`,
+			},
+		},
+		{
+			name: "drop inherited roles child first",
+			from: `
+			CREATE ROLE parent;
+			CREATE ROLE child;
+			CREATE ROLE grandchild;
+			GRANT ROLE parent TO ROLE child;
+			GRANT ROLE child TO ROLE grandchild;
+			GRANT SELECT ON TABLE T1 TO ROLE parent;
+			`,
+			to: ``,
+			expected: []string{
+				`REVOKE ROLE child FROM ROLE grandchild`,
+				`DROP ROLE grandchild`,
+				`REVOKE ROLE parent FROM ROLE child`,
+				`DROP ROLE child`,
+				`DROP ROLE parent`,
+			},
+		},
+		{
+			name: "drop role: revoke only for resources that remain in target",
+			from: `
+				CREATE ROLE role1;
+				CREATE TABLE T1 (id INT64);
+				CREATE TABLE T2 (id INT64);
+				GRANT SELECT ON TABLE T1 TO ROLE role1;
+				GRANT SELECT ON TABLE T2 TO ROLE role1;
+			`,
+			to: `
+				CREATE TABLE T1 (id INT64);
+			`,
+			expected: []string{
+				`DROP TABLE T2`,
+				`REVOKE SELECT ON TABLE T1 FROM ROLE role1`,
+				`DROP ROLE role1`,
+			},
+		},
+		{
+			name: "recreating table and change stream watches multiple tables",
+			from: `
+				CREATE TABLE T1 (id INT64, name STRING(100)) PRIMARY KEY(id, name);
+				CREATE TABLE T2 (id INT64 PRIMARY KEY);
+				CREATE CHANGE STREAM CS1 FOR T1, T2;
+			`,
+			to: `
+				CREATE TABLE T1 (id INT64, name STRING(100) NOT NULL) PRIMARY KEY(id, name);
+				CREATE TABLE T2 (id INT64 PRIMARY KEY);
+				CREATE CHANGE STREAM CS1 FOR T1, T2;
+			`,
+			expected: []string{
+				`ALTER CHANGE STREAM CS1 SET FOR T2`,
+				`DROP TABLE T1`,
+				"CREATE TABLE T1 (\n  id INT64,\n  name STRING(100) NOT NULL\n) PRIMARY KEY (id, name)",
+				`ALTER CHANGE STREAM CS1 SET FOR T1, T2`,
+			},
+		},
+		{
+			name: "recreating table and only change stream is for that table",
+			from: `
+				CREATE TABLE T1 (id INT64, name STRING(100)) PRIMARY KEY(id, name);
+				CREATE CHANGE STREAM CS1 FOR T1;
+			`,
+			to: `
+				CREATE TABLE T1 (id INT64, name STRING(100) NOT NULL) PRIMARY KEY(id, name);
+				CREATE CHANGE STREAM CS1 FOR T1;
+			`,
+			expected: []string{
+				`DROP CHANGE STREAM CS1`,
+				`DROP TABLE T1`,
+				"CREATE TABLE T1 (\n  id INT64,\n  name STRING(100) NOT NULL\n) PRIMARY KEY (id, name)",
+				`CREATE CHANGE STREAM CS1 FOR T1`,
+			},
+		},
+		{
+			name: "drop change stream referenced by multiple tables",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t2_1);
+CREATE CHANGE STREAM SomeStream FOR t1, t2;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t2_1);
+`,
+			ignoreAlterDatabase: true,
+			expected:            []string{"DROP CHANGE STREAM SomeStream"},
+		},
+		{
+			name: "repoint change stream before dropping old table",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t2_1);
+CREATE CHANGE STREAM SomeStream FOR t1;
+`,
+			to: `
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+) PRIMARY KEY(t2_1);
+CREATE CHANGE STREAM SomeStream FOR t2;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM SomeStream SET FOR t2",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "drop table with change stream and grant",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE ROLE role1;
+CREATE CHANGE STREAM CS1 FOR t1;
+GRANT SELECT ON CHANGE STREAM CS1 TO ROLE role1;
+`,
+			to: `
+CREATE ROLE role1;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"DROP CHANGE STREAM CS1",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "avoid duplicate alter change stream set for on drop table",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM SomeStream FOR t1, t2;
+`,
+			to: `
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM SomeStream FOR t2;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM SomeStream SET FOR t2",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "drop change stream when dropping table with remaining tables",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM SomeStream FOR t1, t2;
+`,
+			to: `
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"DROP CHANGE STREAM SomeStream",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "drop two of three tables with multi-table change stream",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t3 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1, t2, t3;
+`,
+			to: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM CS1 SET FOR t1",
+				"DROP TABLE t2",
+				"DROP TABLE t3",
+			},
+		},
+		{
+			name: "change stream from table to FOR ALL with table drop",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1;
+`,
+			to: `
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR ALL;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM CS1 SET FOR ALL",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "change stream from multi-table to FOR ALL with table drop",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1, t2;
+`,
+			to: `
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR ALL;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM CS1 SET FOR ALL",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "change stream from multi-table to none with table drop",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1, t2;
+`,
+			to: `
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM CS1 DROP FOR ALL",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "multiple change streams watching same table",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1;
+CREATE CHANGE STREAM CS2 FOR t1;
+`,
+			to: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"DROP CHANGE STREAM CS2",
+			},
+		},
+		{
+			name: "overlapping change streams on multiple tables",
+			from: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t1, t2;
+CREATE CHANGE STREAM CS2 FOR t2;
+`,
+			to: `
+CREATE TABLE t2 (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE CHANGE STREAM CS1 FOR t2;
+CREATE CHANGE STREAM CS2 FOR t2;
+`,
+			ignoreAlterDatabase: true,
+			expected: []string{
+				"ALTER CHANGE STREAM CS1 SET FOR t2",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "drop index before creating index with the same name on another table",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx1 ON t1(t1_2);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  t2_2 INT64,
+) PRIMARY KEY(t2_1);
+`,
+			to: `
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  t2_2 INT64,
+) PRIMARY KEY(t2_1);
+CREATE INDEX idx1 ON t2(t2_2);
+`,
+			expected: []string{
+				"DROP INDEX idx1",
+				"CREATE INDEX idx1 ON t2(t2_2)",
+				"DROP TABLE t1",
+			},
+		},
+		{
+			name: "rebuild table when primary key changed",
+			from: `
+CREATE ROLE role1;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+  t1_3 INT64 AS (t1_1 * 2) STORED,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+GRANT SELECT ON TABLE t1 TO ROLE role1;
+`,
+			to: `
+CREATE ROLE role1;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+  t1_3 INT64 AS (t1_1 * 2) STORED,
+  t1_4 INT64,
+) PRIMARY KEY(t1_2, t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+GRANT SELECT ON TABLE t1 TO ROLE role1;
+`,
+			rebuildTable: true,
+			expected: []string{
+				"CREATE TABLE t1__hammer_rebuild (\n  t1_1 INT64 NOT NULL,\n  t1_2 STRING(36) NOT NULL,\n  t1_3 INT64 AS (t1_1 * 2) STORED,\n  t1_4 INT64\n) PRIMARY KEY (t1_2, t1_1)",
+				"INSERT OR UPDATE INTO t1__hammer_rebuild (t1_1, t1_2) SELECT t1_1, t1_2 FROM t1",
+				"DROP INDEX idx_t1_2",
+				"ALTER TABLE t2 DROP CONSTRAINT FK_t2",
+				"RENAME TABLE t1 TO t1__hammer_replaced, t1__hammer_rebuild TO t1",
+				"DROP TABLE t1__hammer_replaced",
+				"CREATE INDEX idx_t1_2 ON t1(t1_2)",
+				"ALTER TABLE t2 ADD CONSTRAINT FK_t2 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1)",
+				"GRANT SELECT ON TABLE t1 TO ROLE role1",
+			},
+		},
+		{
+			name: "resume rebuild table",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t1__hammer_rebuild (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+`,
+			rebuildTable: true,
+			expected: []string{
+				"INSERT OR UPDATE INTO t1__hammer_rebuild (t1_1, t1_2) SELECT t1_1, t1_2 FROM t1",
+				"RENAME TABLE t1 TO t1__hammer_replaced, t1__hammer_rebuild TO t1",
+				"DROP TABLE t1__hammer_replaced",
+			},
+		},
+		{
+			name: "resume rebuild table after swapping tables",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+CREATE TABLE t1__hammer_replaced (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1__hammer_replaced(t1_2);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+`,
+			rebuildTable: true,
+			expected: []string{
+				"DROP INDEX idx_t1_2",
+				"CREATE INDEX idx_t1_2 ON t1(t1_2)",
+				"DROP TABLE t1__hammer_replaced",
+			},
+		},
+		{
+			name: "rebuild schema qualified table",
+			from: `
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+`,
+			rebuildTable: true,
+			expected: []string{
+				"CREATE TABLE sch.t1__hammer_rebuild (\n  t1_1 INT64 NOT NULL,\n  t1_2 STRING(36) NOT NULL\n) PRIMARY KEY (t1_2, t1_1)",
+				"INSERT OR UPDATE INTO sch.t1__hammer_rebuild (t1_1, t1_2) SELECT t1_1, t1_2 FROM sch.t1",
+				"RENAME TABLE sch.t1 TO sch.t1__hammer_replaced, sch.t1__hammer_rebuild TO sch.t1",
+				"DROP TABLE sch.t1__hammer_replaced",
+			},
+		},
+		{
+			name: "resume rebuild table after dropping table",
+			from: `
+CREATE TABLE t1__hammer_rebuild (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36) NOT NULL,
+) PRIMARY KEY(t1_2, t1_1);
+CREATE INDEX idx_t1_1 ON t1(t1_1);
+`,
+			rebuildTable: true,
+			expected: []string{
+				"ALTER TABLE t1__hammer_rebuild RENAME TO t1",
+				"CREATE INDEX idx_t1_1 ON t1(t1_1)",
+			},
+		},
+		{
+			name: "rename table with hint",
+			from: `
+CREATE ROLE role1;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36),
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+CREATE TABLE t3 (
+  t3_1 INT64 NOT NULL,
+  CONSTRAINT FK_t3 FOREIGN KEY (t3_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t3_1);
+GRANT SELECT ON TABLE t1 TO ROLE role1;
+`,
+			to: `
+CREATE ROLE role1;
+-- hammer:renamed-from t1
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36),
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t2(t1_2);
+CREATE TABLE t3 (
+  t3_1 INT64 NOT NULL,
+  CONSTRAINT FK_t3 FOREIGN KEY (t3_1) REFERENCES t2 (t1_1),
+) PRIMARY KEY(t3_1);
+GRANT SELECT ON TABLE t2 TO ROLE role1;
+`,
+			expected: []string{
+				"ALTER TABLE t1 RENAME TO t2",
+			},
+		},
+		{
+			name: "rename table with hint and synonym",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1 FROM t1;
+`,
+			to: `
+-- hammer:renamed-from t1
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  SYNONYM(t1),
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t2.t1_1 FROM t2;
+`,
+			expected: []string{
+				"DROP VIEW v1",
+				"ALTER TABLE t1 RENAME TO t2, ADD SYNONYM t1",
+				"CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t2.t1_1 FROM t2",
+			},
+		},
+		{
+			name: "rename schema qualified table with hint",
+			from: `
+CREATE ROLE role1;
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+GRANT SELECT ON TABLE ` + "`sch.t1`" + ` TO ROLE role1;
+`,
+			to: `
+CREATE ROLE role1;
+CREATE SCHEMA sch;
+-- hammer:renamed-from sch.t1
+CREATE TABLE sch.t2 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+GRANT SELECT ON TABLE ` + "`sch.t2`" + ` TO ROLE role1;
+`,
+			expected: []string{
+				"ALTER TABLE sch.t1 RENAME TO t2",
+			},
+		},
+		{
+			name: "rename schema qualified table with hint and change grants and change streams",
+			from: `
+CREATE ROLE role1;
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+GRANT SELECT ON TABLE ` + "`sch.t1`" + ` TO ROLE role1;
+CREATE CHANGE STREAM cs FOR ` + "`sch.t1`" + `;
+`,
+			to: `
+CREATE ROLE role1;
+CREATE SCHEMA sch;
+-- hammer:renamed-from sch.t1
+CREATE TABLE sch.t2 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+GRANT SELECT, INSERT ON TABLE ` + "`sch.t2`" + ` TO ROLE role1;
+CREATE CHANGE STREAM cs FOR ` + "`sch.t2`" + `(t1_2);
+`,
+			expected: []string{
+				"ALTER TABLE sch.t1 RENAME TO t2",
+				"ALTER CHANGE STREAM cs SET FOR sch.t2(t1_2)",
+				"GRANT INSERT ON TABLE sch.t2 TO ROLE role1",
+			},
+		},
+		{
+			name: "ignore rename hint in string literal",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1, "-- hammer:renamed-from t2" AS c FROM t1;
+`,
+			expected: []string{
+				`CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1.t1_1, "-- hammer:renamed-from t2" AS c FROM t1`,
+			},
+		},
+		{
+			name: "rename column with hint",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36),
+  t1_3 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2) STORING (t1_3);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  -- hammer:renamed-from t1_2
+  t1_4 STRING(36),
+  t1_5 INT64 NOT NULL, -- hammer:renamed-from t1_3
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_4) STORING (t1_5);
+`,
+			expected: []string{
+				"DROP INDEX idx_t1_2",
+				"ALTER TABLE t1 ADD COLUMN t1_4 STRING(36)",
+				"UPDATE t1 SET t1_4 = t1_2 WHERE t1_2 IS NOT NULL",
+				"ALTER TABLE t1 ADD COLUMN t1_5 INT64",
+				"UPDATE t1 SET t1_5 = t1_3 WHERE t1_3 IS NOT NULL",
+				"ALTER TABLE t1 ALTER COLUMN t1_5 INT64 NOT NULL",
+				"ALTER TABLE t1 DROP COLUMN t1_2",
+				"ALTER TABLE t1 DROP COLUMN t1_3",
+				"CREATE INDEX idx_t1_2 ON t1(t1_4) STORING (t1_5)",
+			},
+		},
+		{
+			name: "rename hint already applied",
+			from: `
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t1_4 STRING(36),
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+-- hammer:renamed-from t1
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  -- hammer:renamed-from t1_2
+  t1_4 STRING(36),
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{},
+		},
+		{
+			name: "alter column between string and bytes",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36),
+  t1_3 ARRAY<BYTES(MAX)>,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 BYTES(MAX),
+  t1_3 ARRAY<STRING(MAX)>,
+) PRIMARY KEY(t1_1);
+`,
+			expected: []string{
+				"ALTER TABLE t1 ALTER COLUMN t1_2 BYTES(MAX)",
+				"ALTER TABLE t1 ALTER COLUMN t1_3 ARRAY<STRING(MAX)>",
+			},
+		},
+		{
+			name: "convert column type",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+  t1_3 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX) NOT NULL,
+  t1_3 ARRAY<INT64> NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE INDEX idx_t1_2 ON t1(t1_2);
+`,
+			convertColumnType: true,
+			expected: []string{
+				"DROP INDEX idx_t1_2",
+				"ALTER TABLE t1 ADD COLUMN t1_2__hammer_convert STRING(MAX)",
+				"UPDATE t1 SET t1_2__hammer_convert = CAST(t1_2 AS STRING) WHERE t1_2 IS NOT NULL",
+				"ALTER TABLE t1 DROP COLUMN t1_2",
+				"ALTER TABLE t1 ADD COLUMN t1_2 STRING(MAX)",
+				"UPDATE t1 SET t1_2 = t1_2__hammer_convert WHERE t1_2__hammer_convert IS NOT NULL",
+				"ALTER TABLE t1 DROP COLUMN t1_2__hammer_convert",
+				`UPDATE t1 SET t1_2 = "" WHERE t1_2 IS NULL`,
+				"ALTER TABLE t1 ALTER COLUMN t1_2 STRING(MAX) NOT NULL",
+				"CREATE INDEX idx_t1_2 ON t1(t1_2)",
+				"ALTER TABLE t1 ADD COLUMN t1_3__hammer_convert ARRAY<INT64>",
+				"UPDATE t1 SET t1_3__hammer_convert = [CAST(t1_3 AS INT64)] WHERE t1_3 IS NOT NULL",
+				"ALTER TABLE t1 DROP COLUMN t1_3",
+				"ALTER TABLE t1 ADD COLUMN t1_3 ARRAY<INT64>",
+				"UPDATE t1 SET t1_3 = t1_3__hammer_convert WHERE t1_3__hammer_convert IS NOT NULL",
+				"ALTER TABLE t1 DROP COLUMN t1_3__hammer_convert",
+				"ALTER TABLE t1 ALTER COLUMN t1_3 ARRAY<INT64> NOT NULL",
+			},
+		},
+		{
+			name: "convert column type from array to scalar",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 ARRAY<INT64>,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+`,
+			convertColumnType: true,
+			expected: []string{
+				"ALTER TABLE t1 DROP COLUMN t1_2",
+				"ALTER TABLE t1 ADD COLUMN t1_2 INT64",
+			},
+		},
+		{
+			name: "fold alter table statements",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(36),
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+  t2_2 TIMESTAMP,
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1;
+ALTER TABLE t1 ADD COLUMN t1_3 INT64 NOT NULL DEFAULT (0);
+ALTER TABLE t1 DROP COLUMN t1_2;
+ALTER TABLE t1 ALTER COLUMN t1_3 SET DEFAULT (1);
+ALTER TABLE t2 SET ON DELETE CASCADE;
+ALTER TABLE t2 ADD ROW DELETION POLICY (OLDER_THAN(t2_2, INTERVAL 30 DAY));
+ALTER TABLE t2 REPLACE ROW DELETION POLICY (OLDER_THAN(t2_2, INTERVAL 7 DAY));
+ALTER TABLE t2 ALTER COLUMN t2_2 SET OPTIONS (allow_commit_timestamp = true);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_3 INT64 NOT NULL DEFAULT (1),
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+  t2_2 TIMESTAMP OPTIONS (allow_commit_timestamp = true),
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE,
+  ROW DELETION POLICY (OLDER_THAN(t2_2, INTERVAL 7 DAY));
+`,
+			expected: []string{},
+		},
+		{
+			name: "fold alter table constraint and rename statements",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  t2_2 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_2) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+ALTER TABLE t2 DROP CONSTRAINT FK_t2_1;
+ALTER TABLE t2 ADD CONSTRAINT FK_t2_2 FOREIGN KEY (t2_2) REFERENCES t1 (t1_1);
+CREATE TABLE t3 (
+  t3_1 INT64 NOT NULL,
+) PRIMARY KEY(t3_1);
+ALTER TABLE t3 RENAME TO t4;
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t2_1 INT64 NOT NULL,
+  t2_2 INT64 NOT NULL,
+  CONSTRAINT FK_t2_2 FOREIGN KEY (t2_2) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t2_1);
+CREATE TABLE t4 (
+  t3_1 INT64 NOT NULL,
+) PRIMARY KEY(t3_1);
+`,
+			expected: []string{},
+		},
+		{
+			name: "fold rename of table referenced by other objects",
+			from: `
+CREATE ROLE r1;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t1 (t1_1),
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1 ON DELETE CASCADE;
+CREATE CHANGE STREAM cs FOR t1;
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1_1 FROM t1;
+GRANT SELECT ON TABLE t1 TO ROLE r1;
+ALTER TABLE T1 RENAME TO t3;
+`,
+			to: `
+CREATE ROLE r1;
+CREATE TABLE t3 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
+  t2_1 INT64 NOT NULL,
+  CONSTRAINT FK_t2_1 FOREIGN KEY (t2_1) REFERENCES t3 (t1_1),
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t3 ON DELETE CASCADE;
+CREATE CHANGE STREAM cs FOR t3;
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1_1 FROM t3;
+GRANT SELECT ON TABLE t3 TO ROLE r1;
+`,
+			expected: []string{},
+		},
+		{
+			name: "diff schema built with alter table",
+			from: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+ALTER TABLE t1 ADD COLUMN t1_2 STRING(MAX);
+`,
+			expected: []string{
+				"ALTER TABLE t1 ADD COLUMN t1_2 STRING(MAX)",
+			},
+		},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			d1, err := StringSource(v.from).DDL(ctx, &hammer.DDLOption{IgnoreAlterDatabase: v.ignoreAlterDatabase})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			d2, err := StringSource(v.to).DDL(ctx, &hammer.DDLOption{IgnoreAlterDatabase: v.ignoreAlterDatabase})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ddl, err := hammer.Diff(d1, d2, &hammer.DiffOption{RebuildTable: v.rebuildTable, ConvertColumnType: v.convertColumnType})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual := convertStrings(ddl)
+			if diff := cmp.Diff(v.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffRebuildTableWithInterleavedTable(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := StringSource(`
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
+  t1_2 INT64 NOT NULL,
 ) PRIMARY KEY(t1_1);
 CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
   t2_1 INT64 NOT NULL,
-) PRIMARY KEY(t2_1);
-CREATE CHANGE STREAM SomeStream FOR t1, t2;
-`,
-			to: `
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1;
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := StringSource(`
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
-) PRIMARY KEY(t1_1);
+  t1_2 INT64 NOT NULL,
+) PRIMARY KEY(t1_1, t1_2);
 CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
   t2_1 INT64 NOT NULL,
-) PRIMARY KEY(t2_1);
-`,
-			ignoreAlterDatabase: true,
-			expected:            []string{"DROP CHANGE STREAM SomeStream"},
-		},
-		{
-			name: "repoint change stream before dropping old table",
-			from: `
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1;
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := hammer.Diff(from, to, &hammer.DiffOption{RebuildTable: true}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDiffRebuildTableInterleavedInNewParent(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := StringSource(`
 CREATE TABLE t1 (
   t1_1 INT64 NOT NULL,
 ) PRIMARY KEY(t1_1);
 CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
   t2_1 INT64 NOT NULL,
-) PRIMARY KEY(t2_1);
-CREATE CHANGE STREAM SomeStream FOR t1;
-`,
-			to: `
+) PRIMARY KEY(t1_1, t2_1);
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := StringSource(`
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
 CREATE TABLE t2 (
+  t1_1 INT64 NOT NULL,
   t2_1 INT64 NOT NULL,
-) PRIMARY KEY(t2_1);
-CREATE CHANGE STREAM SomeStream FOR t2;
+) PRIMARY KEY(t1_1, t2_1),
+  INTERLEAVE IN PARENT t1;
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = hammer.Diff(from, to, &hammer.DiffOption{RebuildTable: true})
+	if err == nil || !strings.HasPrefix(err.Error(), "string:5:1: cannot rebuild table t2 interleaved in a new parent t1") {
+		t.Fatalf("expected error rebuilding table interleaved in a new parent, got %v", err)
+	}
+}
+
+func TestDiffRepeatable(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := StringSource(`
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1_1 FROM t1;
+ALTER TABLE t1 ALTER COLUMN t1_2 STRING(MAX);
+ALTER TABLE t1 RENAME TO t2;
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := StringSource(`
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 INT64,
+) PRIMARY KEY(t1_1);
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT t1_1 FROM t1;
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source := convertStrings(from)
+
+	first, err := hammer.Diff(from, to, &hammer.DiffOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := hammer.Diff(from, to, &hammer.DiffOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(convertStrings(first), convertStrings(second)); diff != "" {
+		t.Errorf("(-first, +second)\n%s", diff)
+	}
+	if diff := cmp.Diff(source, convertStrings(from)); diff != "" {
+		t.Errorf("the source ddl was changed (-want, +got)\n%s", diff)
+	}
+}
+
+func TestDiffCyclicDependencies(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := StringSource(``).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := StringSource(`
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT v2.c FROM v2;
+CREATE VIEW v2 SQL SECURITY INVOKER AS SELECT v1.c FROM v1;
+`).DDL(ctx, &hammer.DDLOption{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = hammer.Diff(from, to, &hammer.DiffOption{})
+	if err == nil || !strings.HasPrefix(err.Error(), "string:3:1: cannot order statements with cyclic dependencies") {
+		t.Fatalf("expected cyclic dependencies error, got %v", err)
+	}
+}
+
+func TestDiffRemoveFixedDatabaseOptions(t *testing.T) {
+	ctx := context.Background()
+
+	for name, location := range map[string]string{"default_sequence_kind": "string:2:31", "default_time_zone": "string:2:78"} {
+		t.Run(name, func(t *testing.T) {
+			from, err := StringSource(`
+ALTER DATABASE db SET OPTIONS(default_sequence_kind='bit_reversed_positive', default_time_zone='Asia/Tokyo');
+`).DDL(ctx, &hammer.DDLOption{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			kept := "default_time_zone='Asia/Tokyo'"
+			if name == "default_time_zone" {
+				kept = "default_sequence_kind='bit_reversed_positive'"
+			}
+			to, err := StringSource(`ALTER DATABASE db SET OPTIONS(`+kept+`);`).DDL(ctx, &hammer.DDLOption{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = hammer.Diff(from, to, &hammer.DiffOption{})
+			if err == nil || !strings.HasPrefix(err.Error(), location+": cannot remove database option "+name) {
+				t.Fatalf("expected error removing %s, got %v", name, err)
+			}
+		})
+	}
+}
+
+func TestDiffInvalidRoleMembership(t *testing.T) {
+	values := []struct {
+		name     string
+		ddl      string
+		expected string
+	}{
+		{
+			name: "cyclic",
+			ddl: `
+CREATE ROLE r1;
+CREATE ROLE r2;
+CREATE ROLE r3;
+GRANT ROLE r1 TO ROLE r2;
+GRANT ROLE r2 TO ROLE r3;
+GRANT ROLE r3 TO ROLE r1;
 `,
-			ignoreAlterDatabase: true,
-			expected: []string{
-				"ALTER CHANGE STREAM SomeStream SET FOR t2",
-				"DROP TABLE t1",
-			},
+			expected: "string:7:1: cyclic role membership: r1 -> r2 -> r3 -> r1",
 		},
 		{
-			name: "drop table with change stream and grant",
-			from: `
-CREATE TABLE t1 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE ROLE role1;
-CREATE CHANGE STREAM CS1 FOR t1;
-GRANT SELECT ON CHANGE STREAM CS1 TO ROLE role1;
+			name: "dangling",
+			ddl: `
+CREATE ROLE r1;
+GRANT ROLE r1 TO ROLE r2;
 `,
-			to: `
-CREATE ROLE role1;
-`,
-			ignoreAlterDatabase: true,
-			expected: []string{
-				"DROP CHANGE STREAM CS1",
-				"DROP TABLE t1",
-			},
+			expected: "string:3:1: GRANT ROLE r1 TO ROLE r2: role r2 does not exist",
+		},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			ddl, err := StringSource(v.ddl).DDL(ctx, &hammer.DDLOption{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = hammer.Diff(ddl, ddl, nil)
+			if err == nil || err.Error() != v.expected {
+				t.Errorf("expected error %q, got %v", v.expected, err)
+			}
+		})
+	}
+}
+
+func newProtoDescriptors(t *testing.T, file *descriptorpb.FileDescriptorProto) []byte {
+	t.Helper()
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b
+}
+
+func TestDiffProtoBundle(t *testing.T) {
+	itemV1 := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("item.proto"),
+		Package: proto.String("shop"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Item")},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{Name: proto.String("Status"), Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("STATUS_ACTIVE"), Number: proto.Int32(1)},
+			}},
 		},
+	}
+	itemV2 := proto.Clone(itemV1).(*descriptorpb.FileDescriptorProto)
+	itemV2.MessageType[0].Field = []*descriptorpb.FieldDescriptorProto{
+		{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()},
+	}
+
+	values := []struct {
+		name     string
+		from     string
+		fromDesc *descriptorpb.FileDescriptorProto
+		to       string
+		toDesc   *descriptorpb.FileDescriptorProto
+		expected []string
+		err      string
+	}{
 		{
-			name: "avoid duplicate alter change stream set for on drop table",
+			name: "create proto bundle",
 			from: `
 CREATE TABLE t1 (
   id INT64 NOT NULL,
 ) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM SomeStream FOR t1, t2;
 `,
 			to: `
-CREATE TABLE t2 (
+CREATE PROTO BUNDLE (shop.Item, shop.Status);
+CREATE TABLE t1 (
   id INT64 NOT NULL,
+  o shop.Item NOT NULL,
+  s shop.Status NOT NULL,
 ) PRIMARY KEY(id);
-CREATE CHANGE STREAM SomeStream FOR t2;
 `,
-			ignoreAlterDatabase: true,
+			toDesc: itemV1,
 			expected: []string{
-				"ALTER CHANGE STREAM SomeStream SET FOR t2",
-				"DROP TABLE t1",
+				`CREATE PROTO BUNDLE (shop.Item, shop.Status)`,
+				`ALTER TABLE t1 ADD COLUMN o shop.Item NOT NULL DEFAULT (CAST(b"" AS shop.Item))`,
+				`ALTER TABLE t1 ALTER COLUMN o DROP DEFAULT`,
+				`ALTER TABLE t1 ADD COLUMN s shop.Status NOT NULL DEFAULT (CAST(1 AS shop.Status))`,
+				`ALTER TABLE t1 ALTER COLUMN s DROP DEFAULT`,
 			},
 		},
 		{
-			name: "drop change stream when dropping table with remaining tables",
+			name: "add proto column without descriptors",
 			from: `
+CREATE PROTO BUNDLE (shop.Item);
 CREATE TABLE t1 (
   id INT64 NOT NULL,
 ) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM SomeStream FOR t1, t2;
 `,
 			to: `
-CREATE TABLE t2 (
+CREATE PROTO BUNDLE (shop.Item);
+CREATE TABLE t1 (
   id INT64 NOT NULL,
+  o shop.Item NOT NULL,
 ) PRIMARY KEY(id);
 `,
-			ignoreAlterDatabase: true,
-			expected: []string{
-				"DROP CHANGE STREAM SomeStream",
-				"DROP TABLE t1",
-			},
+			err: "string:5:3: cannot add NOT NULL column o without a default value: proto descriptor of shop.Item is required",
 		},
 		{
-			name: "drop two of three tables with multi-table change stream",
+			name: "set NOT NULL to proto column without descriptors",
 			from: `
+CREATE PROTO BUNDLE (shop.Status);
 CREATE TABLE t1 (
   id INT64 NOT NULL,
+  s shop.Status,
 ) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE TABLE t3 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1, t2, t3;
 `,
 			to: `
+CREATE PROTO BUNDLE (shop.Status);
 CREATE TABLE t1 (
   id INT64 NOT NULL,
+  s shop.Status NOT NULL,
 ) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1;
 `,
-			ignoreAlterDatabase: true,
-			expected: []string{
-				"ALTER CHANGE STREAM CS1 SET FOR t1",
-				"DROP TABLE t2",
-				"DROP TABLE t3",
-			},
+			err: "string:5:3: cannot fill NULL values of column s: proto descriptor of shop.Status is required",
 		},
 		{
-			name: "change stream from table to FOR ALL with table drop",
+			name: "unchanged proto bundle without target descriptors",
 			from: `
-CREATE TABLE t1 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1;
+CREATE PROTO BUNDLE (shop.Item);
 `,
+			fromDesc: itemV1,
 			to: `
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR ALL;
+CREATE PROTO BUNDLE (shop.Item);
 `,
-			ignoreAlterDatabase: true,
-			expected: []string{
-				"ALTER CHANGE STREAM CS1 SET FOR ALL",
-				"DROP TABLE t1",
-			},
+			expected: []string{},
 		},
 		{
-			name: "change stream from multi-table to FOR ALL with table drop",
+			name: "insert proto bundle type without descriptors",
+			from: `
+CREATE PROTO BUNDLE (shop.Item);
+`,
+			fromDesc: itemV1,
+			to: `
+CREATE PROTO BUNDLE (shop.Item, shop.Status);
+`,
+			err: "string:2:33: proto descriptor of shop.Status is required to create or update it in the proto bundle",
+		},
+		{
+			name: "set NOT NULL to proto column",
 			from: `
+CREATE PROTO BUNDLE (shop.Item, shop.Status);
 CREATE TABLE t1 (
   id INT64 NOT NULL,
+  s shop.Status,
 ) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1, t2;
 `,
+			fromDesc: itemV1,
 			to: `
-CREATE TABLE t2 (
+CREATE PROTO BUNDLE (shop.Item, shop.Status);
+CREATE TABLE t1 (
   id INT64 NOT NULL,
+  s shop.Status NOT NULL,
 ) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR ALL;
 `,
-			ignoreAlterDatabase: true,
+			toDesc: itemV1,
 			expected: []string{
-				"ALTER CHANGE STREAM CS1 SET FOR ALL",
-				"DROP TABLE t1",
+				`UPDATE t1 SET s = CAST(1 AS shop.Status) WHERE s IS NULL`,
+				`ALTER TABLE t1 ALTER COLUMN s shop.Status NOT NULL`,
 			},
 		},
 		{
-			name: "change stream from multi-table to none with table drop",
+			name: "insert and update proto bundle types",
 			from: `
-CREATE TABLE t1 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1, t2;
+CREATE PROTO BUNDLE (shop.Item);
 `,
+			fromDesc: itemV1,
 			to: `
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1;
+CREATE PROTO BUNDLE (shop.Item, shop.Status);
 `,
-			ignoreAlterDatabase: true,
+			toDesc: itemV2,
 			expected: []string{
-				"ALTER CHANGE STREAM CS1 DROP FOR ALL",
-				"DROP TABLE t1",
+				`ALTER PROTO BUNDLE INSERT (shop.Status) UPDATE (shop.Item)`,
 			},
 		},
 		{
-			name: "multiple change streams watching same table",
+			name: "delete proto bundle types after dropping columns",
 			from: `
+CREATE PROTO BUNDLE (shop.Item);
+ALTER PROTO BUNDLE INSERT (shop.Status);
 CREATE TABLE t1 (
   id INT64 NOT NULL,
+  s shop.Status,
 ) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1;
-CREATE CHANGE STREAM CS2 FOR t1;
 `,
+			fromDesc: itemV1,
 			to: `
+CREATE PROTO BUNDLE (shop.Item);
 CREATE TABLE t1 (
   id INT64 NOT NULL,
 ) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1;
 `,
-			ignoreAlterDatabase: true,
+			toDesc: itemV1,
 			expected: []string{
-				"DROP CHANGE STREAM CS2",
+				`ALTER TABLE t1 DROP COLUMN s`,
+				`ALTER PROTO BUNDLE DELETE (shop.Status)`,
 			},
 		},
 		{
-			name: "overlapping change streams on multiple tables",
+			name: "drop proto bundle",
 			from: `
-CREATE TABLE t1 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t1, t2;
-CREATE CHANGE STREAM CS2 FOR t2;
-`,
-			to: `
-CREATE TABLE t2 (
-  id INT64 NOT NULL,
-) PRIMARY KEY(id);
-CREATE CHANGE STREAM CS1 FOR t2;
-CREATE CHANGE STREAM CS2 FOR t2;
+CREATE PROTO BUNDLE (shop.Item);
 `,
-			ignoreAlterDatabase: true,
+			fromDesc: itemV1,
+			to:       ``,
 			expected: []string{
-				"ALTER CHANGE STREAM CS1 SET FOR t2",
-				"DROP TABLE t1",
+				`DROP PROTO BUNDLE`,
 			},
 		},
 	}
@@ -2639,16 +4957,28 @@ CREATE CHANGE STREAM CS2 FOR t2;
 		t.Run(v.name, func(t *testing.T) {
 			ctx := context.Background()
 
-			d1, err := StringSource(v.from).DDL(ctx, &hammer.DDLOption{IgnoreAlterDatabase: v.ignoreAlterDatabase})
+			d1, err := StringSource(v.from).DDL(ctx, &hammer.DDLOption{})
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
-			d2, err := StringSource(v.to).DDL(ctx, &hammer.DDLOption{IgnoreAlterDatabase: v.ignoreAlterDatabase})
+			if v.fromDesc != nil {
+				d1.ProtoDescriptors = newProtoDescriptors(t, v.fromDesc)
+			}
+			d2, err := StringSource(v.to).DDL(ctx, &hammer.DDLOption{})
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
+			if v.toDesc != nil {
+				d2.ProtoDescriptors = newProtoDescriptors(t, v.toDesc)
+			}
 
-			ddl, err := hammer.Diff(d1, d2)
+			ddl, err := hammer.Diff(d1, d2, &hammer.DiffOption{})
+			if v.err != "" {
+				if err == nil || err.Error() != v.err {
+					t.Fatalf("expected error %q, got %v", v.err, err)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
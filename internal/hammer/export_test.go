@@ -0,0 +1,47 @@
+package hammer
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/option"
+)
+
+// ParseConnectionParams exposes the DSN query parameters parsed by NewClient and the client options of them to the tests.
+func ParseConnectionParams(query url.Values) (credentials, emulator string, createInstance bool, options []option.ClientOption, err error) {
+	params, err := parseConnectionParams(query)
+	if err != nil {
+		return "", "", false, nil, err
+	}
+	return params.credentials, params.emulator, params.createInstance, params.clientOptions(), nil
+}
+
+var ParseDatabasePath = parseDatabasePath
+
+var EmulatorInstanceRequest = emulatorInstanceRequest
+
+// SortStatements exposes the ordering of the statements of a diff from the database "from" to "to" to the tests.
+func SortStatements(from, to, stmts DDL) ([]Statement, error) {
+	database1, err := NewDatabase(from)
+	if err != nil {
+		return nil, err
+	}
+	database2, err := NewDatabase(to)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{from: database1, to: database2, option: &DiffOption{}}
+	return g.sortStatements(stmts.List)
+}
+
+// NewTestClient returns a client using the spanner client, such as one connected to a fake server,
+// which copies tables in commits of the number of cells.
+func NewTestClient(client *spanner.Client, progress io.Writer, copyBatchMutations int) *Client {
+	return &Client{client: client, progress: progress, copyBatchMutations: copyBatchMutations}
+}
+
+func (c *Client) LastCopiedKey(ctx context.Context, stmt CopyTable) (spanner.Key, error) {
+	return c.lastCopiedKey(ctx, stmt)
+}
@@ -0,0 +1,102 @@
+package hammer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cloudspannerecosystem/memefish/ast"
+)
+
+var (
+	createFunctionPattern = regexp.MustCompile(`(?is)^CREATE\s+(OR\s+REPLACE\s+)?FUNCTION\s+([^\s(]+)\s*(\(.*?)\s*;?$`)
+	dropFunctionPattern   = regexp.MustCompile(`(?is)^DROP\s+FUNCTION\s+(IF\s+EXISTS\s+)?([^\s;]+)\s*;?$`)
+	leadingCommentPattern = regexp.MustCompile(`^(\s+|--[^\n]*|#[^\n]*|/\*(?s:.*?)\*/)*`)
+)
+
+// CreateFunction is a CREATE FUNCTION statement of a user-defined function, which the DDL parser doesn't support.
+type CreateFunction struct {
+	OrReplace bool
+	Name      string
+	// Definition is the rest of the statement following the name, such as "(x INT64) RETURNS INT64 AS (x * 2)".
+	Definition string
+}
+
+func (f *CreateFunction) SQL() string {
+	str := "CREATE "
+	if f.OrReplace {
+		str += "OR REPLACE "
+	}
+	return str + "FUNCTION " + f.Name + f.Definition
+}
+
+// DropFunction is a DROP FUNCTION statement.
+type DropFunction struct {
+	IfExists bool
+	Name     string
+}
+
+func (f *DropFunction) SQL() string {
+	str := "DROP FUNCTION "
+	if f.IfExists {
+		str += "IF EXISTS "
+	}
+	return str + f.Name
+}
+
+// parseFunctionStatement parses the statement if it is CREATE FUNCTION or DROP FUNCTION, or returns nil.
+func parseFunctionStatement(stmt string) Statement {
+	stmt = strings.TrimSpace(leadingCommentPattern.ReplaceAllString(stmt, ""))
+	if m := createFunctionPattern.FindStringSubmatch(stmt); m != nil {
+		return &CreateFunction{OrReplace: m[1] != "", Name: m[2], Definition: m[3]}
+	}
+	if m := dropFunctionPattern.FindStringSubmatch(stmt); m != nil {
+		return &DropFunction{IfExists: m[1] != "", Name: m[2]}
+	}
+	return nil
+}
+
+func functionNameEqual(x, y string) bool {
+	return strings.EqualFold(strings.ReplaceAll(x, "`", ""), strings.ReplaceAll(y, "`", ""))
+}
+
+func (g *Generator) findFunctionByName(functions []*CreateFunction, name string) (function *CreateFunction, exists bool) {
+	for _, f := range functions {
+		if functionNameEqual(f.Name, name) {
+			function = f
+			exists = true
+			break
+		}
+	}
+	return
+}
+
+func (g *Generator) functionEqual(x, y *CreateFunction) bool {
+	return strings.Join(strings.Fields(x.Definition), " ") == strings.Join(strings.Fields(y.Definition), " ")
+}
+
+func (g *Generator) generateDDLForDropFunction(function *CreateFunction) DDL {
+	ddl := DDL{}
+	for _, grant := range g.from.grantsOnFunction(function) {
+		if g.isDroppedGrant(grant) {
+			continue
+		}
+		g.droppedGrant = append(g.droppedGrant, grant)
+	}
+	ddl.Append(&DropFunction{Name: function.Name})
+	return ddl
+}
+
+func (d *Database) grantsOnFunction(function *CreateFunction) []*Grant {
+	var result []*Grant
+	for _, grant := range d.grants {
+		if p, exists := grant.Grant.Privilege.(*ast.ExecutePrivilegeOnTableFunction); exists {
+			for _, name := range p.Names {
+				if functionNameEqual(name.Name, function.Name) {
+					result = append(result, grant)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,815 @@
+package hammer
+
+import (
+	"strings"
+
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/cloudspannerecosystem/memefish/token"
+)
+
+// dependencies are the schema objects a statement creates, uses, stops using and removes.
+// Tables and views share the "RELATION" kind, since queries refer to both by the same names.
+// The types of the proto bundle have the "PROTO TYPE" kind, keyed by their fully qualified names.
+type dependencies struct {
+	provides []string
+	requires []string
+	releases []string
+	removes  []string
+	// existing is true if the removed objects exist in the source database,
+	// so that the objects created with the same names in the diff are created after they are removed.
+	existing bool
+}
+
+func relationKey(name string) string      { return "RELATION:" + strings.ToLower(name) }
+func indexKey(name string) string         { return "INDEX:" + strings.ToLower(name) }
+func sequenceKey(name string) string      { return "SEQUENCE:" + strings.ToLower(name) }
+func changeStreamKey(name string) string  { return "CHANGE STREAM:" + strings.ToLower(name) }
+func roleKey(name string) string          { return "ROLE:" + strings.ToLower(name) }
+func schemaKey(name string) string        { return "SCHEMA:" + strings.ToLower(name) }
+func propertyGraphKey(name string) string { return "PROPERTY GRAPH:" + strings.ToLower(name) }
+func localityGroupKey(name string) string { return "LOCALITY GROUP:" + strings.ToLower(name) }
+func protoTypeKey(name string) string     { return "PROTO TYPE:" + strings.ToLower(name) }
+func modelKey(name string) string         { return "MODEL:" + strings.ToLower(name) }
+func functionKey(name string) string {
+	return "FUNCTION:" + strings.ToLower(strings.ReplaceAll(name, "`", ""))
+}
+func columnKey(table, column string) string {
+	return "COLUMN:" + strings.ToLower(table) + "." + strings.ToLower(column)
+}
+
+// sortStatements sorts the statements topologically by the dependencies between the objects they create and drop:
+// an object is created before the statements using it, and dropped after the statements that stop using it.
+// Statements without dependencies between them keep their order. An error is returned if the dependencies are cyclic.
+func (g *Generator) sortStatements(stmts []Statement) ([]Statement, error) {
+	deps := make([]dependencies, len(stmts))
+	for i, stmt := range stmts {
+		deps[i] = g.statementDependencies(stmt)
+	}
+
+	edges := make([][]int, len(stmts))
+	indegree := make([]int, len(stmts))
+	addEdge := func(from, to int) {
+		if from == to {
+			return
+		}
+		edges[from] = append(edges[from], to)
+		indegree[to]++
+	}
+	positions := func(key string, kind func(dependencies) []string) []int {
+		var result []int
+		for i, d := range deps {
+			for _, k := range kind(d) {
+				if k == key {
+					result = append(result, i)
+					break
+				}
+			}
+		}
+		return result
+	}
+	providers := func(d dependencies) []string { return d.provides }
+	removers := func(d dependencies) []string { return d.removes }
+
+	for i, d := range deps {
+		// a statement uses the object created last before it, or else the object created after it
+		// unless it uses the object removed before that.
+		for _, key := range d.requires {
+			provided, removed := -1, -1
+			for _, p := range positions(key, providers) {
+				if p < i || provided == -1 {
+					provided = p
+				}
+			}
+			for _, r := range positions(key, removers) {
+				if r < provided {
+					removed = r
+				}
+			}
+			if provided > i && removed != -1 {
+				continue
+			}
+			if provided != -1 {
+				addEdge(provided, i)
+			}
+		}
+		// a statement stops using the object before the object is removed next, or else before the object
+		// removed last before it unless the object has been created again in between.
+		for _, key := range d.releases {
+			removed := -1
+			for _, r := range positions(key, removers) {
+				removed = r
+				if r > i {
+					break
+				}
+			}
+			if removed == -1 {
+				continue
+			}
+			recreated := false
+			for _, p := range positions(key, providers) {
+				if removed < p && p < i {
+					recreated = true
+				}
+			}
+			if !recreated {
+				addEdge(i, removed)
+			}
+		}
+		// an object is removed before the object with the same name is created again.
+		for _, key := range d.removes {
+			for _, p := range positions(key, providers) {
+				if p > i || d.existing {
+					addEdge(i, p)
+				}
+				if p > i {
+					break
+				}
+			}
+		}
+	}
+
+	sorted := make([]Statement, 0, len(stmts))
+	done := make([]bool, len(stmts))
+	for len(sorted) < len(stmts) {
+		next := -1
+		for i := range stmts {
+			if !done[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			var cyclic []string
+			var node ast.Node
+			for i, stmt := range stmts {
+				if !done[i] {
+					cyclic = append(cyclic, stmt.SQL())
+					if node == nil {
+						node = statementNode(stmt)
+					}
+				}
+			}
+			return nil, g.errorf(node, "cannot order statements with cyclic dependencies: %s", strings.Join(cyclic, "; "))
+		}
+		done[next] = true
+		sorted = append(sorted, stmts[next])
+		for _, to := range edges[next] {
+			indegree[to]--
+		}
+	}
+	return sorted, nil
+}
+
+// statementNode returns the node of the statement, by which it is located in the schema it is parsed from.
+func statementNode(stmt Statement) ast.Node {
+	switch s := stmt.(type) {
+	case *Table:
+		return s.CreateTable
+	case *View:
+		return s.CreateView
+	case *Sequence:
+		return s.CreateSequence
+	case *ChangeStream:
+		return s.CreateChangeStream
+	case *Model:
+		return s.CreateModel
+	case *Schema:
+		return s.CreateSchema
+	case *PropertyGraph:
+		return s.CreatePropertyGraph
+	case *LocalityGroup:
+		return s.CreateLocalityGroup
+	case *Role:
+		return s.CreateRole
+	case *Grant:
+		return s.Grant
+	case AlterColumn:
+		return s.Def
+	case Update:
+		return s.Def
+	case ConvertColumn:
+		return s.To
+	case ast.Node:
+		return s
+	}
+	return nil
+}
+
+// statementDependencies returns the dependencies of the statement.
+// The objects that a drop statement stops using are looked up in the source database.
+func (g *Generator) statementDependencies(stmt Statement) dependencies {
+	var d dependencies
+	switch s := stmt.(type) {
+	case *Table:
+		return g.statementDependencies(s.CreateTable)
+	case *ast.CreateTable:
+		name := identsToComparable(s.Name.Idents...)
+		d.provides = append(d.provides, relationKey(name))
+		d.requires = append(d.requires, schemaKeys(name)...)
+		d.requires = append(d.requires, localityGroupKeys(s.Options)...)
+		if s.Cluster != nil {
+			d.requires = append(d.requires, relationKey(identsToComparable(s.Cluster.TableName.Idents...)))
+		}
+		for _, tc := range s.TableConstraints {
+			if fk, ok := tc.Constraint.(*ast.ForeignKey); ok && !strings.EqualFold(identsToComparable(fk.ReferenceTable.Idents...), name) {
+				d.requires = append(d.requires, relationKey(identsToComparable(fk.ReferenceTable.Idents...)))
+				d.requires = append(d.requires, columnKeys(identsToComparable(fk.ReferenceTable.Idents...), fk.ReferenceColumns)...)
+			}
+		}
+		for _, col := range s.Columns {
+			d.provides = append(d.provides, columnKey(name, col.Name.Name))
+			d.requires = append(d.requires, columnDefKeys(col)...)
+		}
+	case *ast.DropTable:
+		name := identsToComparable(s.Name.Idents...)
+		// the table may be created in the diff, such as the rebuilt table renamed by a swap.
+		d.requires = append(d.requires, relationKey(name))
+		d.removes = append(d.removes, relationKey(name))
+		d.releases = append(d.releases, schemaKeys(name)...)
+		if t, exists := g.findTableByName(g.from.tables, name); exists {
+			d.existing = true
+			d.releases = append(d.releases, localityGroupKeys(t.Options)...)
+			if t.Cluster != nil {
+				d.releases = append(d.releases, relationKey(identsToComparable(t.Cluster.TableName.Idents...)))
+			}
+			for _, col := range t.Columns {
+				d.removes = append(d.removes, columnKey(name, col.Name.Name))
+				d.releases = append(d.releases, columnDefKeys(col)...)
+			}
+			for _, tc := range t.TableConstraints {
+				if fk, ok := tc.Constraint.(*ast.ForeignKey); ok && !strings.EqualFold(identsToComparable(fk.ReferenceTable.Idents...), name) {
+					d.releases = append(d.releases, columnKeys(identsToComparable(fk.ReferenceTable.Idents...), fk.ReferenceColumns)...)
+				}
+			}
+		}
+	case *ast.AlterTable:
+		name := identsToComparable(s.Name.Idents...)
+		switch a := s.TableAlteration.(type) {
+		case *ast.RenameTo:
+			// the table is renamed in its schema.
+			newName := identsToComparable(append(append([]*ast.Ident{}, s.Name.Idents[:len(s.Name.Idents)-1]...), a.Name)...)
+			d.removes = append(d.removes, relationKey(name))
+			d.provides = append(d.provides, relationKey(newName))
+			// the table renamed to may be created in the diff, such as the shadow table of a rebuild.
+			t, exists := g.findTableByName(g.to.tables, newName)
+			if !exists {
+				t, exists = g.findTableByName(g.from.tables, name)
+			}
+			if exists {
+				for _, col := range t.Columns {
+					d.removes = append(d.removes, columnKey(name, col.Name.Name))
+					d.provides = append(d.provides, columnKey(newName, col.Name.Name))
+				}
+			}
+			return d
+		case *ast.AddTableConstraint:
+			if fk, ok := a.TableConstraint.Constraint.(*ast.ForeignKey); ok {
+				d.requires = append(d.requires, relationKey(identsToComparable(fk.ReferenceTable.Idents...)))
+				d.requires = append(d.requires, columnKeys(name, fk.Columns)...)
+				d.requires = append(d.requires, columnKeys(identsToComparable(fk.ReferenceTable.Idents...), fk.ReferenceColumns)...)
+			}
+		case *ast.AddColumn:
+			d.provides = append(d.provides, columnKey(name, a.Column.Name.Name))
+			d.requires = append(d.requires, columnDefKeys(a.Column)...)
+			for _, dep := range generatedColumnDependencies(a.Column) {
+				d.requires = append(d.requires, columnKey(name, dep))
+			}
+		case *ast.DropColumn:
+			d.removes = append(d.removes, columnKey(name, a.Name.Name))
+			if t, exists := g.findTableByName(g.from.tables, name); exists {
+				if col, exists := g.findColumnByName(t.Columns, a.Name.Name); exists {
+					d.releases = append(d.releases, columnDefKeys(col)...)
+					for _, dep := range generatedColumnDependencies(col) {
+						d.releases = append(d.releases, columnKey(name, dep))
+					}
+				}
+			}
+		case *ast.AlterTableSetOptions:
+			d.requires = append(d.requires, localityGroupKeys(a.Options)...)
+			if t, exists := g.findTableByName(g.from.tables, name); exists {
+				d.releases = append(d.releases, localityGroupKeys(t.Options)...)
+			}
+		case *ast.DropConstraint:
+			if t, exists := g.findTableByName(g.from.tables, name); exists {
+				for _, tc := range t.TableConstraints {
+					if fk, ok := tc.Constraint.(*ast.ForeignKey); ok && tc.Name != nil && strings.EqualFold(tc.Name.Name, a.Name.Name) {
+						d.releases = append(d.releases, relationKey(identsToComparable(fk.ReferenceTable.Idents...)))
+						d.releases = append(d.releases, columnKeys(name, fk.Columns)...)
+						d.releases = append(d.releases, columnKeys(identsToComparable(fk.ReferenceTable.Idents...), fk.ReferenceColumns)...)
+					}
+				}
+			}
+		}
+		d.requires = append(d.requires, relationKey(name))
+	case AlterColumn:
+		d.requires = append(d.requires, relationKey(s.Table), columnKey(s.Table, s.Def.Name.Name))
+		if s.Options != nil {
+			d.requires = append(d.requires, localityGroupKeys(s.Options)...)
+		} else {
+			d.requires = append(d.requires, functionKeys(s.Def.DefaultSemantics)...)
+			d.requires = append(d.requires, protoTypeKeys(s.Def.Type)...)
+		}
+		// the altered column stops using the locality group or the type it is altered from.
+		if t, exists := g.findTableByName(g.from.tables, s.Table); exists {
+			if col, exists := g.findColumnByName(t.Columns, s.Def.Name.Name); exists {
+				if s.Options != nil {
+					d.releases = append(d.releases, localityGroupKeys(col.Options)...)
+				} else {
+					d.releases = append(d.releases, protoTypeKeys(col.Type)...)
+				}
+			}
+		}
+	case Update:
+		d.requires = append(d.requires, relationKey(s.Table), columnKey(s.Table, s.Def.Name.Name))
+	case SwapTable:
+		name, shadow, old := identsToComparable(s.Table.Idents...), identsToComparable(s.Shadow.Idents...), identsToComparable(s.Old.Idents...)
+		d.requires = append(d.requires, relationKey(name), relationKey(shadow))
+		d.removes = append(d.removes, relationKey(name), relationKey(shadow))
+		d.provides = append(d.provides, relationKey(name), relationKey(old))
+		if t, exists := g.findTableByName(g.to.tables, name); exists {
+			for _, col := range t.Columns {
+				d.removes = append(d.removes, columnKey(shadow, col.Name.Name))
+				d.provides = append(d.provides, columnKey(name, col.Name.Name))
+			}
+		}
+		return d
+	case CopyTable:
+		d.requires = append(d.requires, relationKey(identsToComparable(s.To.Idents...)))
+		d.releases = append(d.releases, relationKey(identsToComparable(s.From.Idents...)))
+	case ConvertColumn:
+		name := identsToComparable(s.Table.Idents...)
+		d.requires = append(d.requires, relationKey(name), columnKey(name, s.From.Name.Name), columnKey(name, s.To.Name.Name))
+		d.releases = append(d.releases, columnKey(name, s.From.Name.Name))
+	case *ast.CreateIndex:
+		name := identsToComparable(s.Name.Idents...)
+		d.provides = append(d.provides, indexKey(name))
+		d.requires = append(d.requires, schemaKeys(name)...)
+		d.requires = append(d.requires, localityGroupKeys(s.Options)...)
+		d.requires = append(d.requires, relationKey(identsToComparable(s.TableName.Idents...)))
+		d.requires = append(d.requires, indexColumnKeys(s)...)
+	case *ast.DropIndex:
+		name := identsToComparable(s.Name.Idents...)
+		d.removes = append(d.removes, indexKey(name))
+		d.releases = append(d.releases, schemaKeys(name)...)
+		for _, t := range g.from.tables {
+			if i, exists := g.findIndexByName(t.indexes, name); exists {
+				d.existing = true
+				d.releases = append(d.releases, relationKey(identsToComparable(t.Name.Idents...)))
+				d.releases = append(d.releases, localityGroupKeys(i.Options)...)
+				d.releases = append(d.releases, indexColumnKeys(i)...)
+			}
+		}
+	case *ast.AlterIndex:
+		name := identsToComparable(s.Name.Idents...)
+		d.requires = append(d.requires, indexKey(name))
+		for _, t := range g.from.tables {
+			if _, exists := g.findIndexByName(t.indexes, name); exists {
+				d.requires = append(d.requires, storedColumnKeys(identsToComparable(t.Name.Idents...), s.IndexAlteration)...)
+			}
+		}
+	case *ast.CreateSearchIndex:
+		d.provides = append(d.provides, indexKey(s.Name.Name))
+		d.requires = append(d.requires, relationKey(s.TableName.Name))
+		d.requires = append(d.requires, searchIndexColumnKeys(s)...)
+	case *ast.DropSearchIndex:
+		d.removes = append(d.removes, indexKey(s.Name.Name))
+		for _, t := range g.from.tables {
+			if i, exists := g.findSearchIndexByName(t.searchIndexes, s.Name.Name); exists {
+				d.existing = true
+				d.releases = append(d.releases, relationKey(identsToComparable(t.Name.Idents...)))
+				d.releases = append(d.releases, searchIndexColumnKeys(i)...)
+			}
+		}
+	case *ast.AlterSearchIndex:
+		d.requires = append(d.requires, indexKey(s.Name.Name))
+		for _, t := range g.from.tables {
+			if _, exists := g.findSearchIndexByName(t.searchIndexes, s.Name.Name); exists {
+				d.requires = append(d.requires, storedColumnKeys(identsToComparable(t.Name.Idents...), s.IndexAlteration)...)
+			}
+		}
+	case *ast.CreateVectorIndex:
+		d.provides = append(d.provides, indexKey(s.Name.Name))
+		d.requires = append(d.requires, relationKey(s.TableName.Name))
+		d.requires = append(d.requires, vectorIndexColumnKeys(s)...)
+	case *ast.DropVectorIndex:
+		d.removes = append(d.removes, indexKey(s.Name.Name))
+		for _, t := range g.from.tables {
+			if i, exists := g.findVectorIndexByName(t.vectorIndexes, s.Name.Name); exists {
+				d.existing = true
+				d.releases = append(d.releases, relationKey(identsToComparable(t.Name.Idents...)))
+				d.releases = append(d.releases, vectorIndexColumnKeys(i)...)
+			}
+		}
+	case *View:
+		return g.statementDependencies(s.CreateView)
+	case *ast.CreateView:
+		name := identsToComparable(s.Name.Idents...)
+		d.provides = append(d.provides, relationKey(name))
+		d.requires = append(d.requires, schemaKeys(name)...)
+		d.requires = append(d.requires, viewKeys(&View{CreateView: s}, g.to)...)
+		// the replaced view stops using the objects that the new query no longer uses.
+		if v, exists := g.findViewByName(g.from.views, name); exists && s.OrReplace {
+			for _, key := range viewKeys(v, g.from) {
+				if !containsKey(d.requires, key) {
+					d.releases = append(d.releases, key)
+				}
+			}
+		}
+	case *ast.DropView:
+		name := identsToComparable(s.Name.Idents...)
+		d.removes = append(d.removes, relationKey(name))
+		d.releases = append(d.releases, schemaKeys(name)...)
+		if v, exists := g.findViewByName(g.from.views, name); exists {
+			d.releases = append(d.releases, viewKeys(v, g.from)...)
+		}
+	case *CreateFunction:
+		d.provides = append(d.provides, functionKey(s.Name))
+		d.requires = append(d.requires, schemaKeys(strings.ReplaceAll(s.Name, "`", ""))...)
+		d.requires = append(d.requires, functionRelationKeys(s, g.to)...)
+	case *DropFunction:
+		d.removes = append(d.removes, functionKey(s.Name))
+		d.releases = append(d.releases, schemaKeys(strings.ReplaceAll(s.Name, "`", ""))...)
+		if f, exists := g.findFunctionByName(g.from.functions, s.Name); exists {
+			d.releases = append(d.releases, functionRelationKeys(f, g.from)...)
+		}
+	case *Sequence:
+		return g.statementDependencies(s.CreateSequence)
+	case *ast.CreateSequence:
+		name := identsToComparable(s.Name.Idents...)
+		d.provides = append(d.provides, sequenceKey(name))
+		d.requires = append(d.requires, schemaKeys(name)...)
+	case *ast.DropSequence:
+		name := identsToComparable(s.Name.Idents...)
+		d.removes = append(d.removes, sequenceKey(name))
+		d.releases = append(d.releases, schemaKeys(name)...)
+	case *ChangeStream:
+		return g.statementDependencies(s.CreateChangeStream)
+	case *ast.CreateChangeStream:
+		d.provides = append(d.provides, changeStreamKey(s.Name.Name))
+		d.requires = append(d.requires, changeStreamTableKeys(s.For)...)
+	case *ast.AlterChangeStream:
+		if a, ok := s.ChangeStreamAlteration.(*ast.ChangeStreamSetFor); ok {
+			d.requires = append(d.requires, changeStreamTableKeys(a.For)...)
+		}
+		d.requires = append(d.requires, changeStreamKey(s.Name.Name))
+	case *ast.DropChangeStream:
+		d.removes = append(d.removes, changeStreamKey(s.Name.Name))
+		if cs, exists := g.findChangeStreamByName(g.from, s.Name.Name); exists {
+			d.releases = append(d.releases, changeStreamTableKeys(cs.For)...)
+		}
+	case *Role:
+		return g.statementDependencies(s.CreateRole)
+	case *ast.CreateRole:
+		d.provides = append(d.provides, roleKey(s.Name.Name))
+	case *ast.DropRole:
+		d.removes = append(d.removes, roleKey(s.Name.Name))
+	case *Grant:
+		return g.statementDependencies(s.Grant)
+	case *ast.Grant:
+		d.requires = append(d.requires, privilegeKeys(s.Privilege, s.Roles)...)
+	case *ast.Revoke:
+		d.releases = append(d.releases, privilegeKeys(s.Privilege, s.Roles)...)
+	case *Schema:
+		return g.statementDependencies(s.CreateSchema)
+	case *ast.CreateSchema:
+		d.provides = append(d.provides, schemaKey(s.Name.Name))
+	case *ast.DropSchema:
+		d.removes = append(d.removes, schemaKey(s.Name.Name))
+	case *PropertyGraph:
+		return g.statementDependencies(s.CreatePropertyGraph)
+	case *ast.CreatePropertyGraph:
+		d.provides = append(d.provides, propertyGraphKey(s.Name.Name))
+		d.requires = append(d.requires, propertyGraphTableKeys(s.Content)...)
+		// the replaced property graph stops using the tables that the new one no longer uses.
+		if pg, exists := g.findPropertyGraphByName(g.from.propertyGraphs, s.Name.Name); exists && s.OrReplace {
+			for _, key := range propertyGraphTableKeys(pg.Content) {
+				if !containsKey(d.requires, key) {
+					d.releases = append(d.releases, key)
+				}
+			}
+		}
+	case *ast.DropPropertyGraph:
+		d.removes = append(d.removes, propertyGraphKey(s.Name.Name))
+		if pg, exists := g.findPropertyGraphByName(g.from.propertyGraphs, s.Name.Name); exists {
+			d.releases = append(d.releases, propertyGraphTableKeys(pg.Content)...)
+		}
+	case *LocalityGroup:
+		return g.statementDependencies(s.CreateLocalityGroup)
+	case *ast.CreateLocalityGroup:
+		d.provides = append(d.provides, localityGroupKey(s.Name.Name))
+	case *ast.AlterLocalityGroup:
+		d.requires = append(d.requires, localityGroupKey(s.Name.Name))
+	case *ast.DropLocalityGroup:
+		d.removes = append(d.removes, localityGroupKey(s.Name.Name))
+	case *ast.CreateProtoBundle:
+		d.provides = append(d.provides, protoBundleKeys(s.Types)...)
+	case *ast.AlterProtoBundle:
+		if s.Insert != nil {
+			d.provides = append(d.provides, protoBundleKeys(s.Insert.Types)...)
+		}
+		if s.Update != nil {
+			d.requires = append(d.requires, protoBundleKeys(s.Update.Types)...)
+		}
+		if s.Delete != nil {
+			d.removes = append(d.removes, protoBundleKeys(s.Delete.Types)...)
+		}
+	case *ast.DropProtoBundle:
+		if g.from.protoBundle != nil {
+			d.removes = append(d.removes, protoBundleKeys(&ast.ProtoBundleTypes{Types: g.from.protoBundle.types})...)
+		}
+	case *Model:
+		return g.statementDependencies(s.CreateModel)
+	case *ast.CreateModel:
+		d.provides = append(d.provides, modelKey(s.Name.Name))
+	case *ast.AlterModel:
+		d.requires = append(d.requires, modelKey(s.Name.Name))
+	case *ast.DropModel:
+		d.removes = append(d.removes, modelKey(s.Name.Name))
+	}
+	return d
+}
+
+// schemaKeys returns the key of the schema of a schema-qualified name, such as "sch" of "sch.t1".
+func schemaKeys(name string) []string {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return []string{schemaKey(name[:i])}
+	}
+	return nil
+}
+
+// columnDefKeys returns the keys of the sequences, the functions, the locality group and the proto types used by the column.
+func columnDefKeys(col *ast.ColumnDef) []string {
+	keys := sequenceKeys(col)
+	keys = append(keys, functionKeys(col.DefaultSemantics)...)
+	keys = append(keys, localityGroupKeys(col.Options)...)
+	return append(keys, protoTypeKeys(col.Type)...)
+}
+
+// localityGroupKeys returns the key of the locality group given by the locality_group option.
+func localityGroupKeys(options *ast.Options) []string {
+	if v := optionsValueFromName(options, "locality_group"); v != nil {
+		if lit, ok := (*v).(*ast.StringLiteral); ok {
+			return []string{localityGroupKey(lit.Value)}
+		}
+	}
+	return nil
+}
+
+// protoTypeKeys returns the key of the proto or enum type of the column type, or of its elements.
+func protoTypeKeys(t ast.SchemaType) []string {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		return []string{protoTypeKey(identsToComparable(t.Path...))}
+	case *ast.ArraySchemaType:
+		return protoTypeKeys(t.Item)
+	}
+	return nil
+}
+
+func protoBundleKeys(types *ast.ProtoBundleTypes) []string {
+	var keys []string
+	if types == nil {
+		return keys
+	}
+	for _, t := range types.Types {
+		keys = append(keys, protoTypeKey(identsToComparable(t.Path...)))
+	}
+	return keys
+}
+
+// propertyGraphTableKeys returns the keys of the node and edge tables of the property graph.
+func propertyGraphTableKeys(content *ast.PropertyGraphContent) []string {
+	var keys []string
+	if t := content.NodeTables; t != nil {
+		for _, e := range t.Tables.Elements {
+			keys = append(keys, relationKey(e.Name.Name))
+		}
+	}
+	if t := content.EdgeTables; t != nil {
+		for _, e := range t.Tables.Elements {
+			keys = append(keys, relationKey(e.Name.Name))
+		}
+	}
+	return keys
+}
+
+// sequenceKeys returns the keys of the sequences used by the default value of the column.
+func sequenceKeys(col *ast.ColumnDef) []string {
+	var keys []string
+	if col.DefaultSemantics == nil {
+		return keys
+	}
+	ast.Inspect(col.DefaultSemantics, func(n ast.Node) bool {
+		if arg, ok := n.(*ast.SequenceArg); ok {
+			switch e := arg.Expr.(type) {
+			case *ast.Ident:
+				keys = append(keys, sequenceKey(e.Name))
+			case *ast.Path:
+				keys = append(keys, sequenceKey(identsToComparable(e.Idents...)))
+			}
+		}
+		return true
+	})
+	return keys
+}
+
+// functionKeys returns the keys of the functions called in the node.
+// Built-in functions are included, but no statement creates or drops them.
+func functionKeys(node ast.Node) []string {
+	var keys []string
+	if node == nil {
+		return keys
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			keys = append(keys, functionKey(identsToComparable(call.Func.Idents...)))
+		}
+		return true
+	})
+	return keys
+}
+
+// viewKeys returns the keys of the models, the relations, the columns and the functions used by the view.
+// The columns are those of the tables of the database the view reads from, whose names appear in the query.
+func viewKeys(v *View, database *Database) []string {
+	var idents []string
+	ast.Inspect(v.Query, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			idents = append(idents, ident.Name)
+		}
+		return true
+	})
+	var keys []string
+	ast.Inspect(v.Query, func(n ast.Node) bool {
+		if arg, ok := n.(*ast.ModelArg); ok {
+			keys = append(keys, modelKey(identsToComparable(arg.Name.Idents...)))
+		}
+		return true
+	})
+	for _, ref := range v.references() {
+		keys = append(keys, relationKey(ref))
+		for _, t := range database.tables {
+			if !strings.EqualFold(identsToComparable(t.Name.Idents...), ref) {
+				continue
+			}
+			for _, col := range t.Columns {
+				for _, ident := range idents {
+					if strings.EqualFold(ident, col.Name.Name) {
+						keys = append(keys, columnKey(ref, col.Name.Name))
+						break
+					}
+				}
+			}
+		}
+	}
+	return append(keys, functionKeys(v.Query)...)
+}
+
+// columnKeys returns the keys of the columns of the table.
+func columnKeys(table string, columns []*ast.Ident) []string {
+	var keys []string
+	for _, col := range columns {
+		keys = append(keys, columnKey(table, col.Name))
+	}
+	return keys
+}
+
+// indexColumnKeys returns the keys of the key and stored columns of the index.
+func indexColumnKeys(i *ast.CreateIndex) []string {
+	table := identsToComparable(i.TableName.Idents...)
+	var keys []string
+	for _, key := range i.Keys {
+		keys = append(keys, columnKey(table, key.Name.Name))
+	}
+	return append(keys, columnKeys(table, storingColumns(i.Storing))...)
+}
+
+func searchIndexColumnKeys(i *ast.CreateSearchIndex) []string {
+	table := i.TableName.Name
+	keys := columnKeys(table, i.TokenListPart)
+	keys = append(keys, columnKeys(table, i.PartitionColumns)...)
+	if i.OrderBy != nil {
+		for _, item := range i.OrderBy.Items {
+			if ident, ok := item.Expr.(*ast.Ident); ok {
+				keys = append(keys, columnKey(table, ident.Name))
+			}
+		}
+	}
+	return append(keys, columnKeys(table, storingColumns(i.Storing))...)
+}
+
+func vectorIndexColumnKeys(i *ast.CreateVectorIndex) []string {
+	keys := []string{columnKey(i.TableName.Name, i.ColumnName.Name)}
+	return append(keys, columnKeys(i.TableName.Name, storingColumns(i.Storing))...)
+}
+
+// storedColumnKeys returns the keys of the columns added to the index by the alteration.
+func storedColumnKeys(table string, alteration ast.IndexAlteration) []string {
+	if a, ok := alteration.(*ast.AddStoredColumn); ok {
+		return []string{columnKey(table, a.Name.Name)}
+	}
+	return nil
+}
+
+// functionRelationKeys returns the keys of the tables and views of the database that the function definition names.
+// The definition is not parsed, so the names are matched as paths of identifiers in its tokens,
+// which excludes string literals and comments.
+func functionRelationKeys(f *CreateFunction, database *Database) []string {
+	var names []string
+	for _, t := range database.tables {
+		names = append(names, identsToComparable(t.Name.Idents...))
+	}
+	for _, v := range database.views {
+		names = append(names, identsToComparable(v.Name.Idents...))
+	}
+	var keys []string
+	for _, path := range identifierPaths(f.Definition) {
+		// a path may name a column or a field of the relation, such as t1.c1, so each of its prefixes is matched.
+		for i := range path {
+			for _, name := range names {
+				if strings.EqualFold(strings.Join(path[:i+1], "."), name) {
+					keys = append(keys, relationKey(name))
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// identifierPaths returns the paths of identifiers in the sql, such as ["sch", "t1"] of "sch.t1",
+// with the quotes of the identifiers removed. The paths read before a lexical error are returned.
+func identifierPaths(sql string) [][]string {
+	lexer := &memefish.Lexer{File: &token.File{Buffer: sql}}
+	var paths [][]string
+	var path []string
+	dot := false
+	for lexer.NextToken() == nil && lexer.Token.Kind != token.TokenEOF {
+		switch {
+		case lexer.Token.Kind == token.TokenIdent && dot && len(path) > 0:
+			path = append(path, lexer.Token.AsString)
+		case lexer.Token.Kind == token.TokenIdent:
+			if len(path) > 0 {
+				paths = append(paths, path)
+			}
+			path = []string{lexer.Token.AsString}
+		case lexer.Token.Kind == "." && len(path) > 0:
+		default:
+			if len(path) > 0 {
+				paths = append(paths, path)
+			}
+			path = nil
+		}
+		dot = lexer.Token.Kind == "."
+	}
+	if len(path) > 0 {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func changeStreamTableKeys(f ast.ChangeStreamFor) []string {
+	var keys []string
+	if f, ok := f.(*ast.ChangeStreamForTables); ok {
+		for _, t := range f.Tables {
+			keys = append(keys, relationKey(identsToComparable(t.TableName)))
+		}
+	}
+	return keys
+}
+
+// privilegeKeys returns the keys of the objects and the roles of a grant or a revoke.
+func privilegeKeys(privilege ast.Privilege, roles []*ast.Ident) []string {
+	var keys []string
+	switch p := privilege.(type) {
+	case *ast.PrivilegeOnTable:
+		for _, name := range p.Names {
+			keys = append(keys, relationKey(name.Name))
+		}
+	case *ast.SelectPrivilegeOnView:
+		for _, name := range p.Names {
+			keys = append(keys, relationKey(name.Name))
+		}
+	case *ast.SelectPrivilegeOnChangeStream:
+		for _, name := range p.Names {
+			keys = append(keys, changeStreamKey(name.Name))
+		}
+	case *ast.RolePrivilege:
+		for _, name := range p.Names {
+			keys = append(keys, roleKey(name.Name))
+		}
+	}
+	for _, r := range roles {
+		keys = append(keys, roleKey(r.Name))
+	}
+	return keys
+}
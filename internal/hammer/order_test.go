@@ -0,0 +1,302 @@
+package hammer_test
+
+import (
+	"testing"
+
+	"github.com/daichirata/hammer/internal/hammer"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSortStatements(t *testing.T) {
+	values := []struct {
+		name     string
+		from     string
+		to       string
+		stmts    string
+		expected []string
+	}{
+		{
+			name: "create schema before its objects",
+			to: `
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEQUENCE sch.s1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+`,
+			stmts: `
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE SEQUENCE sch.s1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+CREATE SCHEMA sch;
+`,
+			expected: []string{
+				"CREATE SCHEMA sch",
+				"CREATE TABLE sch.t1 (\n  t1_1 INT64 NOT NULL\n) PRIMARY KEY (t1_1)",
+				`CREATE SEQUENCE sch.s1 OPTIONS (sequence_kind = "bit_reversed_positive")`,
+			},
+		},
+		{
+			name: "drop schema after its objects",
+			from: `
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE VIEW sch.v1 SQL SECURITY INVOKER AS SELECT t1_1 FROM sch.t1;
+`,
+			stmts: `
+DROP SCHEMA sch;
+DROP VIEW sch.v1;
+DROP TABLE sch.t1;
+`,
+			expected: []string{
+				"DROP VIEW sch.v1",
+				"DROP TABLE sch.t1",
+				"DROP SCHEMA sch",
+			},
+		},
+		{
+			name: "rename table in its schema before the index on the new name",
+			from: `
+CREATE SCHEMA sch;
+CREATE TABLE sch.t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			to: `
+CREATE SCHEMA sch;
+CREATE TABLE sch.t2 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+CREATE INDEX sch.idx1 ON sch.t2(t1_1);
+`,
+			stmts: `
+CREATE INDEX sch.idx1 ON sch.t2(t1_1);
+ALTER TABLE sch.t1 RENAME TO t2;
+`,
+			expected: []string{
+				"ALTER TABLE sch.t1 RENAME TO t2",
+				"CREATE INDEX sch.idx1 ON sch.t2(t1_1)",
+			},
+		},
+		{
+			name: "create property graph after its tables",
+			to: `
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person);
+`,
+			stmts: `
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person);
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+`,
+			expected: []string{
+				"CREATE TABLE Person (\n  id INT64 NOT NULL\n) PRIMARY KEY (id)",
+				"CREATE PROPERTY GRAPH FinGraph NODE TABLES (Person)",
+			},
+		},
+		{
+			name: "drop property graph before its tables",
+			from: `
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person);
+`,
+			stmts: `
+DROP TABLE Person;
+DROP PROPERTY GRAPH FinGraph;
+`,
+			expected: []string{
+				"DROP PROPERTY GRAPH FinGraph",
+				"DROP TABLE Person",
+			},
+		},
+		{
+			name: "replace property graph before dropping the table it no longer uses",
+			from: `
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE TABLE Account (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person, Account);
+`,
+			to: `
+CREATE TABLE Person (
+  id INT64 NOT NULL,
+) PRIMARY KEY(id);
+CREATE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person);
+`,
+			stmts: `
+DROP TABLE Account;
+CREATE OR REPLACE PROPERTY GRAPH FinGraph
+  NODE TABLES (Person);
+`,
+			expected: []string{
+				"CREATE OR REPLACE PROPERTY GRAPH FinGraph NODE TABLES (Person)",
+				"DROP TABLE Account",
+			},
+		},
+		{
+			name: "create locality group before the table and column placed in it",
+			to: `
+CREATE LOCALITY GROUP lg1;
+CREATE LOCALITY GROUP lg2;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX) OPTIONS (locality_group = 'lg2'),
+) PRIMARY KEY(t1_1), OPTIONS (locality_group = 'lg1');
+`,
+			stmts: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 STRING(MAX) OPTIONS (locality_group = 'lg2'),
+) PRIMARY KEY(t1_1), OPTIONS (locality_group = 'lg1');
+CREATE LOCALITY GROUP lg1;
+CREATE LOCALITY GROUP lg2;
+`,
+			expected: []string{
+				"CREATE LOCALITY GROUP lg1",
+				"CREATE LOCALITY GROUP lg2",
+				"CREATE TABLE t1 (\n  t1_1 INT64 NOT NULL,\n  t1_2 STRING(MAX) OPTIONS (locality_group = \"lg2\")\n) PRIMARY KEY (t1_1), OPTIONS (locality_group = \"lg1\")",
+			},
+		},
+		{
+			name: "drop locality group after moving the table out of it",
+			from: `
+CREATE LOCALITY GROUP lg1;
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1), OPTIONS (locality_group = 'lg1');
+`,
+			to: `
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			stmts: `
+DROP LOCALITY GROUP lg1;
+ALTER TABLE t1 SET OPTIONS (locality_group = null);
+`,
+			expected: []string{
+				"ALTER TABLE t1 SET OPTIONS (locality_group = null)",
+				"DROP LOCALITY GROUP lg1",
+			},
+		},
+		{
+			name: "alter locality group after creating it",
+			stmts: `
+ALTER LOCALITY GROUP lg1 SET OPTIONS (storage = 'ssd');
+CREATE LOCALITY GROUP lg1;
+`,
+			expected: []string{
+				"CREATE LOCALITY GROUP lg1",
+				`ALTER LOCALITY GROUP lg1 SET OPTIONS (storage = "ssd")`,
+			},
+		},
+		{
+			name: "insert proto bundle type before the column using it",
+			from: `
+CREATE PROTO BUNDLE (examples.Singer);
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+) PRIMARY KEY(t1_1);
+`,
+			stmts: `
+ALTER TABLE t1 ADD COLUMN t1_2 ARRAY<examples.Genre>;
+ALTER PROTO BUNDLE INSERT (examples.Genre);
+`,
+			expected: []string{
+				"ALTER PROTO BUNDLE INSERT (examples.Genre)",
+				"ALTER TABLE t1 ADD COLUMN t1_2 ARRAY<examples.Genre>",
+			},
+		},
+		{
+			name: "drop proto bundle after the table using its types",
+			from: `
+CREATE PROTO BUNDLE (examples.Singer);
+CREATE TABLE t1 (
+  t1_1 INT64 NOT NULL,
+  t1_2 examples.Singer,
+) PRIMARY KEY(t1_1);
+`,
+			stmts: `
+DROP PROTO BUNDLE;
+DROP TABLE t1;
+`,
+			expected: []string{
+				"DROP TABLE t1",
+				"DROP PROTO BUNDLE",
+			},
+		},
+		{
+			name: "create model before the view using it",
+			to: `
+CREATE TABLE t1 (
+  id INT64 NOT NULL,
+  prompt STRING(MAX),
+) PRIMARY KEY(id);
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT content FROM ML.PREDICT(MODEL m1, TABLE t1);
+`,
+			stmts: `
+CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT content FROM ML.PREDICT(MODEL m1, TABLE t1);
+ALTER MODEL m1 SET OPTIONS (default_batch_size = 1);
+CREATE MODEL m1
+INPUT (prompt STRING(MAX))
+OUTPUT (content STRING(MAX))
+REMOTE
+OPTIONS (endpoint = '//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m');
+`,
+			expected: []string{
+				`CREATE MODEL m1 INPUT (prompt STRING(MAX)) OUTPUT (content STRING(MAX)) REMOTE OPTIONS (endpoint = "//aiplatform.googleapis.com/projects/p/locations/l/publishers/google/models/m")`,
+				"CREATE VIEW v1 SQL SECURITY INVOKER AS SELECT content FROM ML.PREDICT(MODEL m1, TABLE t1)",
+				"ALTER MODEL m1 SET OPTIONS (default_batch_size = 1)",
+			},
+		},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			from, err := hammer.ParseDDL("from", v.from, &hammer.DDLOption{})
+			if err != nil {
+				t.Fatalf("failed to parse from ddl: %v", err)
+			}
+			to, err := hammer.ParseDDL("to", v.to, &hammer.DDLOption{})
+			if err != nil {
+				t.Fatalf("failed to parse to ddl: %v", err)
+			}
+			stmts, err := hammer.ParseDDL("stmts", v.stmts, &hammer.DDLOption{})
+			if err != nil {
+				t.Fatalf("failed to parse statements: %v", err)
+			}
+
+			sorted, err := hammer.SortStatements(from, to, stmts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual := make([]string, 0, len(sorted))
+			for _, stmt := range sorted {
+				actual = append(actual, stmt.SQL())
+			}
+			if diff := cmp.Diff(v.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package hammer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ReadProtoDescriptors reads a serialized FileDescriptorSet used by CREATE/ALTER PROTO BUNDLE statements.
+func ReadProtoDescriptors(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseProtoDescriptors(b); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return b, nil
+}
+
+type ProtoBundle struct {
+	types []*ast.NamedType
+}
+
+func (b *ProtoBundle) findType(name string) (*ast.NamedType, bool) {
+	for _, t := range b.types {
+		if identsToComparable(t.Path...) == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (b *ProtoBundle) insert(types ...*ast.NamedType) {
+	for _, t := range types {
+		if _, exists := b.findType(identsToComparable(t.Path...)); !exists {
+			b.types = append(b.types, t)
+		}
+	}
+}
+
+func (b *ProtoBundle) delete(types ...*ast.NamedType) error {
+	for _, t := range types {
+		name := identsToComparable(t.Path...)
+		found := false
+		for i, bt := range b.types {
+			if identsToComparable(bt.Path...) == name {
+				b.types = append(b.types[:i], b.types[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("cannot find proto bundle type to delete %s", t.SQL())
+		}
+	}
+	return nil
+}
+
+type protoDescriptor struct {
+	raw []byte
+	// firstEnumValue is the number of the first value if the descriptor is an enum.
+	firstEnumValue *int32
+}
+
+// parseProtoDescriptors returns the descriptors of all messages and enums in the FileDescriptorSet
+// keyed by their fully qualified names.
+func parseProtoDescriptors(b []byte) (map[string]*protoDescriptor, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &fds); err != nil {
+		return nil, fmt.Errorf("failed to parse proto descriptors: %s", err)
+	}
+
+	descriptors := make(map[string]*protoDescriptor)
+	marshal := proto.MarshalOptions{Deterministic: true}
+
+	var addEnums func(prefix string, enums []*descriptorpb.EnumDescriptorProto) error
+	addEnums = func(prefix string, enums []*descriptorpb.EnumDescriptorProto) error {
+		for _, e := range enums {
+			raw, err := marshal.Marshal(e)
+			if err != nil {
+				return err
+			}
+			d := &protoDescriptor{raw: raw}
+			if len(e.Value) > 0 {
+				n := e.Value[0].GetNumber()
+				d.firstEnumValue = &n
+			}
+			descriptors[prefix+e.GetName()] = d
+		}
+		return nil
+	}
+	var addMessages func(prefix string, messages []*descriptorpb.DescriptorProto) error
+	addMessages = func(prefix string, messages []*descriptorpb.DescriptorProto) error {
+		for _, m := range messages {
+			raw, err := marshal.Marshal(m)
+			if err != nil {
+				return err
+			}
+			name := prefix + m.GetName()
+			descriptors[name] = &protoDescriptor{raw: raw}
+			if err := addMessages(name+".", m.NestedType); err != nil {
+				return err
+			}
+			if err := addEnums(name+".", m.EnumType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, f := range fds.File {
+		prefix := ""
+		if f.GetPackage() != "" {
+			prefix = f.GetPackage() + "."
+		}
+		if err := addMessages(prefix, f.MessageType); err != nil {
+			return nil, fmt.Errorf("failed to parse proto descriptors: %s", err)
+		}
+		if err := addEnums(prefix, f.EnumType); err != nil {
+			return nil, fmt.Errorf("failed to parse proto descriptors: %s", err)
+		}
+	}
+	return descriptors, nil
+}
+
+// protoDescriptorEqual returns true if the descriptor of the type is unchanged in the other database.
+// The type is considered unchanged if the other database has no descriptors, e.g. a schema file applied
+// without the descriptors file.
+func (d *Database) protoDescriptorEqual(other *Database, name string) bool {
+	if len(other.protoDescriptors) == 0 {
+		return true
+	}
+	x, xok := d.protoDescriptors[name]
+	y, yok := other.protoDescriptors[name]
+	if !xok || !yok {
+		return xok == yok
+	}
+	return bytes.Equal(x.raw, y.raw)
+}
+
+// protoDefaultValue returns the zero value of the proto or enum type, or nil if the type is unknown.
+func (d *Database) protoDefaultValue(t *ast.NamedType) ast.Expr {
+	desc, ok := d.protoDescriptors[identsToComparable(t.Path...)]
+	if !ok {
+		return nil
+	}
+	if desc.firstEnumValue != nil {
+		return &ast.CastExpr{Expr: &ast.IntLiteral{Value: fmt.Sprint(*desc.firstEnumValue)}, Type: t}
+	}
+	return &ast.CastExpr{Expr: &ast.BytesLiteral{Value: nil}, Type: t}
+}
+
+// isProtoEnum returns true if the type is known to be an enum.
+func (d *Database) isProtoEnum(t *ast.NamedType) bool {
+	desc, ok := d.protoDescriptors[identsToComparable(t.Path...)]
+	return ok && desc.firstEnumValue != nil
+}
+
+// isProtoMessage returns true if the type is known to be a proto message.
+func (d *Database) isProtoMessage(t *ast.NamedType) bool {
+	desc, ok := d.protoDescriptors[identsToComparable(t.Path...)]
+	return ok && desc.firstEnumValue == nil
+}
@@ -0,0 +1,291 @@
+package hammer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
+	"github.com/cloudspannerecosystem/memefish/token"
+)
+
+var renamedFromPattern = regexp.MustCompile(`^--[ \t]*hammer:renamed-from[ \t]+(\S+)`)
+
+// Rename is a hint that a table or a column was renamed, so that it is renamed instead of dropped and created.
+type Rename struct {
+	// Table is the new name of the table.
+	Table string
+	// Column is the new name of the column, or empty if the table was renamed.
+	Column string
+	// From is the old name of the table or the column.
+	From string
+}
+
+// ParseRename parses a rename hint in the format "OLD_TABLE=NEW_TABLE" or "TABLE.OLD_COLUMN=NEW_COLUMN",
+// where TABLE is the new name of the table.
+func ParseRename(s string) (Rename, error) {
+	from, to, ok := strings.Cut(s, "=")
+	if !ok || from == "" || to == "" {
+		return Rename{}, fmt.Errorf("invalid rename %q: must be OLD=NEW", s)
+	}
+	if strings.Count(from, ".") > strings.Count(to, ".") {
+		i := strings.LastIndex(from, ".")
+		return Rename{Table: from[:i], Column: to, From: from[i+1:]}, nil
+	}
+	return Rename{Table: to, From: from}, nil
+}
+
+// parseRenameHints reads "-- hammer:renamed-from OLD_NAME" annotations in the comments of the schema.
+// An annotation on its own line applies to the following CREATE TABLE statement or column definition,
+// and an annotation after a column definition applies to that column.
+func parseRenameHints(file *token.File, stmts []ast.DDL) ([]Rename, error) {
+	comments, err := readComments(file)
+	if err != nil {
+		return nil, err
+	}
+	schema := file.Buffer
+	var renames []Rename
+	for _, comment := range comments {
+		match := renamedFromPattern.FindStringSubmatch(comment.Raw)
+		if match == nil {
+			continue
+		}
+		pos := int(comment.Pos)
+		from := strings.Trim(match[1], "`")
+		lineStart := strings.LastIndex(schema[:pos], "\n") + 1
+		trailing := strings.TrimSpace(schema[lineStart:pos]) != ""
+
+		var stmt ast.DDL
+		for _, s := range stmts {
+			if int(s.End()) > pos {
+				stmt = s
+				break
+			}
+		}
+		table, ok := stmt.(*ast.CreateTable)
+		if !ok {
+			return nil, fmt.Errorf("%s: hammer:renamed-from %s must annotate a table or a column", location{file: file, pos: token.Pos(pos)}, from)
+		}
+		name := identsToComparable(table.Name.Idents...)
+
+		if !trailing && pos < int(table.Pos()) {
+			renames = append(renames, Rename{Table: name, From: from})
+			continue
+		}
+		var col *ast.ColumnDef
+		for _, c := range table.Columns {
+			if trailing && int(c.End()) <= pos {
+				col = c
+			}
+			if !trailing && int(c.Pos()) > pos {
+				col = c
+				break
+			}
+		}
+		if col == nil {
+			return nil, fmt.Errorf("%s: hammer:renamed-from %s must annotate a table or a column", location{file: file, pos: token.Pos(pos)}, from)
+		}
+		renames = append(renames, Rename{Table: name, Column: col.Name.Name, From: from})
+	}
+	return renames, nil
+}
+
+// readComments returns the comments in the file, skipping string literals and quoted identifiers
+// which may contain text that looks like a comment.
+func readComments(file *token.File) ([]token.TokenComment, error) {
+	lexer := &memefish.Lexer{File: file}
+	var comments []token.TokenComment
+	for {
+		if err := lexer.NextToken(); err != nil {
+			return nil, err
+		}
+		comments = append(comments, lexer.Token.Comments...)
+		if lexer.Token.Kind == token.TokenEOF {
+			return comments, nil
+		}
+	}
+}
+
+func (g *Generator) generateDDLForRenames() DDL {
+	ddl := DDL{}
+
+	for _, r := range g.to.renames {
+		if r.Column != "" {
+			continue
+		}
+		fromTable, exists := g.findTableByName(g.from.tables, r.From)
+		if !exists {
+			continue
+		}
+		if _, exists := g.findTableByName(g.from.tables, r.Table); exists {
+			continue
+		}
+		toTable, exists := g.findTableByName(g.to.tables, r.Table)
+		if !exists {
+			continue
+		}
+		rename := &ast.RenameTo{Name: toTable.Name.Idents[len(toTable.Name.Idents)-1]}
+		oldName := fromTable.Name.Idents[len(fromTable.Name.Idents)-1]
+		if _, exists := g.findSynonymByName(toTable.Synonyms, oldName.Name); exists {
+			if _, exists := g.findSynonymByName(fromTable.Synonyms, oldName.Name); !exists {
+				rename.AddSynonym = &ast.AddSynonym{Name: oldName}
+			}
+		}
+		ddl.Append(&ast.AlterTable{Name: fromTable.Name, TableAlteration: rename})
+		g.renamedTable = append(g.renamedTable, identsToComparable(fromTable.Name.Idents...), identsToComparable(toTable.Name.Idents...))
+		g.from.renameTable(fromTable, rename)
+	}
+	for _, r := range g.to.renames {
+		if r.Column == "" {
+			continue
+		}
+		fromTable, exists := g.findTableByName(g.from.tables, r.Table)
+		if !exists {
+			continue
+		}
+		toTable, exists := g.findTableByName(g.to.tables, r.Table)
+		if !exists {
+			continue
+		}
+		for _, toCol := range toTable.Columns {
+			if _, exists := g.findRenamedColumn(fromTable, toTable, toCol); exists {
+				g.renamedTable = append(g.renamedTable, identsToComparable(fromTable.Name.Idents...))
+				break
+			}
+		}
+	}
+	return ddl
+}
+
+// findRenamedColumn returns the column in "from" that the column in "to" is renamed from by a hint.
+// Hints whose old column no longer exists or whose new column already exists are ignored.
+func (g *Generator) findRenamedColumn(from, to *Table, toCol *ast.ColumnDef) (col *ast.ColumnDef, exists bool) {
+	for _, r := range g.to.renames {
+		if r.Column == "" || !strings.EqualFold(r.Table, identsToComparable(to.Name.Idents...)) || !strings.EqualFold(r.Column, toCol.Name.Name) {
+			continue
+		}
+		if _, exists := g.findColumnByName(from.Columns, r.Column); exists {
+			continue
+		}
+		fromCol, exists := g.findColumnByName(from.Columns, r.From)
+		if !exists {
+			continue
+		}
+		if _, exists := g.findColumnByName(to.Columns, r.From); exists {
+			continue
+		}
+		return fromCol, true
+	}
+	return nil, false
+}
+
+// generateDDLForRenameColumn renames the column by adding the new column, copying the values of the old column
+// into it by partitioned DML, and dropping the old column, since Spanner cannot rename columns.
+func (g *Generator) generateDDLForRenameColumn(to *Table, fromCol, toCol *ast.ColumnDef) DDL {
+	ddl := DDL{}
+
+	if columnConvertExpr(fromCol, toCol) == "" {
+		g.fail(g.errorf(toCol, "cannot rename column %s of table %s to %s: %s cannot be converted into %s", fromCol.Name.SQL(), to.Name.SQL(), toCol.Name.SQL(), fromCol.Type.SQL(), toCol.Type.SQL()))
+		return ddl
+	}
+	newCol := *toCol
+	newCol.NotNull = false
+	ddl.Append(&ast.AlterTable{Name: to.Name, TableAlteration: &ast.AddColumn{Column: &newCol}})
+	ddl.Append(ConvertColumn{Table: to.Name, From: fromCol, To: &newCol})
+	if toCol.NotNull {
+		if !fromCol.NotNull {
+			ddl.AppendDDL(g.generateUpdateForNullValues(to.Name, toCol))
+		}
+		ddl.Append(AlterColumn{Table: to.Name.SQL(), Def: toCol})
+	}
+	return ddl
+}
+
+func (g *Generator) findSynonymByName(synonyms []*ast.Synonym, name string) (synonym *ast.Synonym, exists bool) {
+	for _, s := range synonyms {
+		if strings.EqualFold(s.Name.Name, name) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// renameTable renames the table and the references to it, as Spanner does on ALTER TABLE RENAME TO.
+func (d *Database) renameTable(table *Table, rename *ast.RenameTo) {
+	oldName := identsToComparable(table.Name.Idents...)
+	// alter never fails for RENAME TO.
+	_ = table.alter(rename)
+	d.renameTableReferences(oldName, table)
+}
+
+// renameTableReferences rewrites the references to the table renamed from oldName.
+func (d *Database) renameTableReferences(oldName string, table *Table) {
+	// grants, change streams and table names in queries name the table by a single identifier,
+	// which holds the schema of a schema-qualified name and is printed by QualifiedTableNames.
+	newName := &ast.Ident{Name: identsToComparable(table.Name.Idents...)}
+
+	for _, t := range d.tables {
+		if t.Cluster != nil && strings.EqualFold(identsToComparable(t.Cluster.TableName.Idents...), oldName) {
+			t.Cluster.TableName = table.Name
+		}
+		for _, tc := range t.TableConstraints {
+			if fk, ok := tc.Constraint.(*ast.ForeignKey); ok && strings.EqualFold(identsToComparable(fk.ReferenceTable.Idents...), oldName) {
+				fk.ReferenceTable = table.Name
+			}
+		}
+	}
+	for _, grant := range d.grants {
+		if p, ok := grant.Privilege.(*ast.PrivilegeOnTable); ok {
+			for i, name := range p.Names {
+				if strings.EqualFold(identsToComparable(name), oldName) {
+					p.Names[i] = newName
+				}
+			}
+		}
+	}
+	for _, cs := range d.allChangeStreams() {
+		if f, ok := cs.For.(*ast.ChangeStreamForTables); ok {
+			for _, t := range f.Tables {
+				if strings.EqualFold(identsToComparable(t.TableName), oldName) {
+					t.TableName = newName
+				}
+			}
+		}
+	}
+	for _, v := range d.views {
+		ast.Inspect(v.Query, func(n ast.Node) bool {
+			switch t := n.(type) {
+			case *ast.TableName:
+				if strings.EqualFold(identsToComparable(t.Table), oldName) {
+					t.Table = newName
+				}
+			case *ast.PathTableExpr:
+				if strings.EqualFold(identsToComparable(t.Path.Idents...), oldName) {
+					t.Path = table.Name
+				}
+			}
+			return true
+		})
+	}
+}
+
+// allChangeStreams returns the change streams of the database, including those watching specific tables.
+func (d *Database) allChangeStreams() []*ChangeStream {
+	changeStreams := append([]*ChangeStream{}, d.changeStreams...)
+	for _, t := range d.tables {
+		for _, cs := range t.changeStreams {
+			found := false
+			for _, c := range changeStreams {
+				if c.CreateChangeStream == cs.CreateChangeStream {
+					found = true
+					break
+				}
+			}
+			if !found {
+				changeStreams = append(changeStreams, cs)
+			}
+		}
+	}
+	return changeStreams
+}
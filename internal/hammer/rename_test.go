@@ -0,0 +1,59 @@
+package hammer_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/daichirata/hammer/internal/hammer"
+)
+
+func TestParseRename(t *testing.T) {
+	values := []struct {
+		s        string
+		expected hammer.Rename
+		wantErr  bool
+	}{
+		{
+			s:        "t1=t2",
+			expected: hammer.Rename{Table: "t2", From: "t1"},
+		},
+		{
+			s:        "s1.t1=s1.t2",
+			expected: hammer.Rename{Table: "s1.t2", From: "s1.t1"},
+		},
+		{
+			s:        "t2.c1=c2",
+			expected: hammer.Rename{Table: "t2", Column: "c2", From: "c1"},
+		},
+		{
+			s:        "s1.t2.c1=c2",
+			expected: hammer.Rename{Table: "s1.t2", Column: "c2", From: "c1"},
+		},
+		{
+			s:       "t1",
+			wantErr: true,
+		},
+		{
+			s:       "t1=",
+			wantErr: true,
+		},
+	}
+	for _, v := range values {
+		t.Run(v.s, func(t *testing.T) {
+			actual, err := hammer.ParseRename(v.s)
+			if v.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(v.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package hammer
+
+import (
+	"strings"
+
+	"github.com/cloudspannerecosystem/memefish/ast"
+)
+
+// systemRoles are the roles predefined by Spanner, which can be granted without CREATE ROLE.
+var systemRoles = []string{"public", "spanner_info_reader", "spanner_sys_reader"}
+
+// roleGraph is the role membership graph built from GRANT ROLE statements.
+// The children of a role are the roles it is granted to, which inherit its privileges.
+type roleGraph struct {
+	children map[string][]string
+}
+
+// newRoleGraph builds the role membership graph of the ddl, and returns an error if a granted role does not exist
+// or if the membership is cyclic.
+func newRoleGraph(ddl DDL, roles []*Role, grants []*Grant) (*roleGraph, error) {
+	defined := make(map[string]bool)
+	for _, r := range systemRoles {
+		defined[r] = true
+	}
+	for _, r := range roles {
+		defined[strings.ToLower(r.Name.Name)] = true
+	}
+
+	g := &roleGraph{children: make(map[string][]string)}
+	granted := make(map[[2]string]*Grant)
+	for _, grant := range grants {
+		p, ok := grant.Privilege.(*ast.RolePrivilege)
+		if !ok {
+			continue
+		}
+		for _, name := range append(append([]*ast.Ident{}, p.Names...), grant.Roles...) {
+			if !defined[strings.ToLower(name.Name)] {
+				return nil, ddl.errorf(grant.Grant, "%s: role %s does not exist", grant.SQL(), name.SQL())
+			}
+		}
+		for _, parent := range p.Names {
+			for _, child := range grant.Roles {
+				g.children[strings.ToLower(parent.Name)] = append(g.children[strings.ToLower(parent.Name)], strings.ToLower(child.Name))
+				granted[[2]string{strings.ToLower(parent.Name), strings.ToLower(child.Name)}] = grant
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(role string) error
+	visit = func(role string) error {
+		switch state[role] {
+		case visiting:
+			for i, r := range path {
+				if r == role {
+					grant := granted[[2]string{path[len(path)-1], role}]
+					return ddl.errorf(grant.Grant, "cyclic role membership: %s", strings.Join(append(path[i:], role), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[role] = visiting
+		path = append(path, role)
+		for _, child := range g.children[role] {
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[role] = visited
+		return nil
+	}
+	for _, grant := range grants {
+		if p, ok := grant.Privilege.(*ast.RolePrivilege); ok {
+			for _, parent := range p.Names {
+				if err := visit(strings.ToLower(parent.Name)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// childFirst sorts the roles so that each role comes before the roles it inherits.
+func (g *roleGraph) childFirst(roles []*Role) []*Role {
+	index := make(map[string]*Role)
+	for _, r := range roles {
+		index[strings.ToLower(r.Name.Name)] = r
+	}
+
+	var sorted []*Role
+	visited := make(map[string]bool)
+	var visit func(role string)
+	visit = func(role string) {
+		if visited[role] {
+			return
+		}
+		visited[role] = true
+		for _, child := range g.children[role] {
+			visit(child)
+		}
+		if r, ok := index[role]; ok {
+			sorted = append(sorted, r)
+		}
+	}
+	for _, r := range roles {
+		visit(strings.ToLower(r.Name.Name))
+	}
+	return sorted
+}
+
+// grantsOfRole returns the grants of the role to other roles.
+func (d *Database) grantsOfRole(role *Role) []*Grant {
+	var result []*Grant
+	for _, grant := range d.grants {
+		p, ok := grant.Privilege.(*ast.RolePrivilege)
+		if !ok {
+			continue
+		}
+		for _, name := range p.Names {
+			if strings.EqualFold(name.Name, role.Name.Name) {
+				result = append(result, grant)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -1,11 +1,15 @@
 package hammer
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 type Source interface {
@@ -25,6 +29,8 @@ func NewSource(ctx context.Context, uri string) (Source, error) {
 		return NewSpannerSource(ctx, uri)
 	case "file", "":
 		return NewFileSource(uri)
+	case "git":
+		return NewGitSource(uri)
 	}
 	return nil, errors.New("invalid source")
 }
@@ -47,11 +53,16 @@ func (s *SpannerSource) String() string {
 }
 
 func (s *SpannerSource) DDL(ctx context.Context, option *DDLOption) (DDL, error) {
-	schema, err := s.client.GetDatabaseDDL(ctx)
+	schema, protoDescriptors, err := s.client.GetDatabaseDDL(ctx)
 	if err != nil {
 		return DDL{}, err
 	}
-	return ParseDDL(s.uri, schema, option)
+	ddl, err := ParseDDL(s.uri, schema, option)
+	if err != nil {
+		return DDL{}, err
+	}
+	ddl.ProtoDescriptors = protoDescriptors
+	return ddl, nil
 }
 
 func (s *SpannerSource) Apply(ctx context.Context, ddl DDL) error {
@@ -62,17 +73,20 @@ func (s *SpannerSource) Create(ctx context.Context, ddl DDL) error {
 	return s.client.CreateDatabase(ctx, ddl)
 }
 
+// manifestFileName is the name of the file listing the schema files of a directory in the order to read them.
+// Each line is a path relative to the directory. Empty lines and lines starting with "#" are ignored.
+const manifestFileName = "hammer.manifest"
+
+// FileSource reads the schema from a file, from the files in a directory, or from the files matching a glob pattern.
+// The files in a directory are read in the order listed in its manifest file, or in lexical order of the "*.sql" files.
 type FileSource struct {
 	uri  string
 	path string
 }
 
 func NewFileSource(uri string) (*FileSource, error) {
-	u, err := url.Parse(uri)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse uri: %s", err)
-	}
-	return &FileSource{uri: uri, path: u.Path}, nil
+	// The path is kept as is rather than parsed as a url, since "?" in a glob pattern would start a query.
+	return &FileSource{uri: uri, path: strings.TrimPrefix(uri, "file://")}, nil
 }
 
 func (s *FileSource) String() string {
@@ -80,9 +94,207 @@ func (s *FileSource) String() string {
 }
 
 func (s *FileSource) DDL(_ context.Context, option *DDLOption) (DDL, error) {
-	schema, err := os.ReadFile(s.path)
+	return readSchemaFiles(localFiles{}, s.path, option)
+}
+
+// GitSource reads the schema from a file, from the files in a directory, or from the files matching a glob pattern
+// at a revision of the git repository in the current directory, without checking it out.
+// The uri is in the format "git:REV:PATH", where PATH is relative to the current directory.
+type GitSource struct {
+	uri      string
+	revision string
+	path     string
+}
+
+func NewGitSource(uri string) (*GitSource, error) {
+	parts := strings.SplitN(uri, ":", 3)
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid git source %q: must be git:REV:PATH", uri)
+	}
+	return &GitSource{uri: uri, revision: parts[1], path: parts[2]}, nil
+}
+
+func (s *GitSource) String() string {
+	return s.uri
+}
+
+func (s *GitSource) DDL(ctx context.Context, option *DDLOption) (DDL, error) {
+	return readSchemaFiles(gitFiles{ctx: ctx, revision: s.revision}, s.path, option)
+}
+
+// schemaFiles is the storage of schema files.
+type schemaFiles interface {
+	// name returns the name of the file in error messages.
+	name(path string) string
+	isDir(path string) (bool, error)
+	readFile(path string) ([]byte, error)
+	glob(pattern string) ([]string, error)
+}
+
+// readSchemaFiles parses the schema files at the path, and returns an error if an object is defined in more than one file.
+func readSchemaFiles(files schemaFiles, path string, option *DDLOption) (DDL, error) {
+	paths, err := listSchemaFiles(files, path)
 	if err != nil {
 		return DDL{}, err
 	}
-	return ParseDDL(s.uri, string(schema), option)
+	ddl := DDL{}
+	for _, path := range paths {
+		schema, err := files.readFile(path)
+		if err != nil {
+			return DDL{}, err
+		}
+		fileDDL, err := ParseDDL(files.name(path), string(schema), option)
+		if err != nil {
+			return DDL{}, err
+		}
+		ddl.AppendDDL(fileDDL)
+		ddl.Renames = append(ddl.Renames, fileDDL.Renames...)
+	}
+	if err := ddl.validateDefinitions(); err != nil {
+		return DDL{}, err
+	}
+	return ddl, nil
+}
+
+// listSchemaFiles returns the paths of the schema files at the path in the order to read them.
+func listSchemaFiles(files schemaFiles, path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		paths, err := files.glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %s", files.name(path), err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no schema files match %s", files.name(path))
+		}
+		sort.Strings(paths)
+		return paths, nil
+	}
+
+	dir, err := files.isDir(path)
+	if err != nil {
+		return nil, err
+	}
+	if !dir {
+		return []string{path}, nil
+	}
+
+	manifests, err := files.glob(filepath.Join(path, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) > 0 {
+		manifest, err := files.readFile(manifests[0])
+		if err != nil {
+			return nil, err
+		}
+		var paths []string
+		for _, line := range strings.Split(string(manifest), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			paths = append(paths, filepath.Join(path, line))
+		}
+		return paths, nil
+	}
+	paths, err := files.glob(filepath.Join(path, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no schema files in %s", files.name(path))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// localFiles are the schema files in the local file system.
+type localFiles struct{}
+
+func (localFiles) name(path string) string {
+	return path
+}
+
+func (localFiles) isDir(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (localFiles) readFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (localFiles) glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// gitFiles are the schema files at a revision of the git repository in the current directory.
+// Glob patterns are supported only in the last element of the path.
+type gitFiles struct {
+	ctx      context.Context
+	revision string
+}
+
+func (g gitFiles) name(path string) string {
+	return "git:" + g.revision + ":" + path
+}
+
+// object returns the name of the git object of the path, relative to the current directory.
+func (g gitFiles) object(path string) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	switch {
+	case path == ".":
+		path = "./"
+	case path != ".." && !strings.HasPrefix(path, "../"):
+		path = "./" + path
+	}
+	return g.revision + ":" + path
+}
+
+func (g gitFiles) isDir(path string) (bool, error) {
+	out, err := g.git("cat-file", "-t", g.object(path))
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "tree", nil
+}
+
+func (g gitFiles) readFile(path string) ([]byte, error) {
+	return g.git("cat-file", "blob", g.object(path))
+}
+
+func (g gitFiles) glob(pattern string) ([]string, error) {
+	dir, base := filepath.Split(pattern)
+	if strings.ContainsAny(dir, "*?[") {
+		return nil, errors.New("glob patterns are supported only in the last element of the path")
+	}
+	out, err := g.git("ls-tree", "--name-only", g.object(dir))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		matched, err := filepath.Match(base, name)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths, nil
+}
+
+func (g gitFiles) git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(g.ctx, "git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
 }
@@ -0,0 +1,246 @@
+package hammer_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/daichirata/hammer/internal/hammer"
+)
+
+func TestFileSource(t *testing.T) {
+	values := []struct {
+		name     string
+		files    map[string]string
+		path     string
+		expected []string
+		err      string
+	}{
+		{
+			name: "directory",
+			files: map[string]string{
+				"users.sql":   "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"billing.sql": "CREATE TABLE Invoices (InvoiceID INT64 NOT NULL) PRIMARY KEY(InvoiceID);",
+				"README.md":   "not a schema",
+			},
+			expected: []string{
+				"CREATE TABLE Invoices (\n  InvoiceID INT64 NOT NULL\n) PRIMARY KEY (InvoiceID)",
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+			},
+		},
+		{
+			name: "directory with manifest",
+			files: map[string]string{
+				"users.sql":       "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"billing.sql":     "CREATE TABLE Invoices (InvoiceID INT64 NOT NULL) PRIMARY KEY(InvoiceID);",
+				"hammer.manifest": "# schema files\nusers.sql\n\nbilling.sql\n",
+			},
+			expected: []string{
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+				"CREATE TABLE Invoices (\n  InvoiceID INT64 NOT NULL\n) PRIMARY KEY (InvoiceID)",
+			},
+		},
+		{
+			name: "glob",
+			files: map[string]string{
+				"users.sql":   "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"billing.sql": "CREATE TABLE Invoices (InvoiceID INT64 NOT NULL) PRIMARY KEY(InvoiceID);",
+			},
+			path: "u*.sql",
+			expected: []string{
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+			},
+		},
+		{
+			name: "glob with single character wildcard",
+			files: map[string]string{
+				"users1.sql":  "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"users2.sql":  "CREATE TABLE Invoices (InvoiceID INT64 NOT NULL) PRIMARY KEY(InvoiceID);",
+				"users10.sql": "CREATE TABLE Items (ItemID INT64 NOT NULL) PRIMARY KEY(ItemID);",
+			},
+			path: "users?.sql",
+			expected: []string{
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+				"CREATE TABLE Invoices (\n  InvoiceID INT64 NOT NULL\n) PRIMARY KEY (InvoiceID)",
+			},
+		},
+		{
+			name: "parse error",
+			files: map[string]string{
+				"users.sql":   "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"billing.sql": "CREATE TABLE Invoices (\n  InvoiceID INT64 NOT NULL,\n PRIMARY KEY(InvoiceID);",
+			},
+			err: "billing.sql:3:",
+		},
+		{
+			name: "duplicate definition",
+			files: map[string]string{
+				"users.sql":   "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"billing.sql": "\nCREATE TABLE users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+			},
+			err: "users.sql:1:1: table Users is already defined at ",
+		},
+		{
+			name: "duplicate definition in a single file",
+			files: map[string]string{
+				"users.sql": "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);\nCREATE TABLE users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+			},
+			err: "users.sql:2:1: table users is already defined at ",
+		},
+		{
+			name: "redefinition after drop",
+			files: map[string]string{
+				"a.sql": "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);",
+				"b.sql": "DROP TABLE Users;\nCREATE TABLE Users (UserID STRING(36) NOT NULL) PRIMARY KEY(UserID);",
+			},
+			expected: []string{
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+				"DROP TABLE Users",
+				"CREATE TABLE Users (\n  UserID STRING(36) NOT NULL\n) PRIMARY KEY (UserID)",
+			},
+		},
+		{
+			name: "create or replace",
+			files: map[string]string{
+				"a.sql": "CREATE VIEW V SQL SECURITY INVOKER AS SELECT 1 AS C;",
+				"b.sql": "CREATE OR REPLACE VIEW V SQL SECURITY INVOKER AS SELECT 2 AS C;",
+			},
+			expected: []string{
+				"CREATE VIEW V SQL SECURITY INVOKER AS SELECT 1 AS C",
+				"CREATE OR REPLACE VIEW V SQL SECURITY INVOKER AS SELECT 2 AS C",
+			},
+		},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range v.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			source, err := hammer.NewFileSource("file://" + filepath.Join(dir, v.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ddl, err := source.DDL(context.Background(), &hammer.DDLOption{})
+			if v.err != "" {
+				if err == nil || !strings.Contains(err.Error(), v.err) {
+					t.Fatalf("got %v want error containing %q", err, v.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := make([]string, len(ddl.List))
+			for i, stmt := range ddl.List {
+				actual[i] = stmt.SQL()
+			}
+			if diff := cmp.Diff(v.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	write("schema.sql", "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);")
+	write("schema/users.sql", "CREATE TABLE Users (UserID INT64 NOT NULL) PRIMARY KEY(UserID);")
+	write("schema/billing.sql", "CREATE TABLE Invoices (InvoiceID INT64 NOT NULL) PRIMARY KEY(InvoiceID);")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	write("schema.sql", "CREATE TABLE Accounts (AccountID INT64 NOT NULL) PRIMARY KEY(AccountID);")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	values := []struct {
+		uri      string
+		expected []string
+		err      string
+	}{
+		{
+			uri: "git:HEAD:schema.sql",
+			expected: []string{
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+			},
+		},
+		{
+			uri: "git:HEAD:schema",
+			expected: []string{
+				"CREATE TABLE Invoices (\n  InvoiceID INT64 NOT NULL\n) PRIMARY KEY (InvoiceID)",
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+			},
+		},
+		{
+			uri: "git:HEAD:schema/u*.sql",
+			expected: []string{
+				"CREATE TABLE Users (\n  UserID INT64 NOT NULL\n) PRIMARY KEY (UserID)",
+			},
+		},
+		{
+			uri: "git:HEAD:missing.sql",
+			err: "git cat-file -t HEAD:./missing.sql",
+		},
+	}
+	for _, v := range values {
+		t.Run(v.uri, func(t *testing.T) {
+			source, err := hammer.NewSource(context.Background(), v.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ddl, err := source.DDL(context.Background(), &hammer.DDLOption{})
+			if v.err != "" {
+				if err == nil || !strings.Contains(err.Error(), v.err) {
+					t.Fatalf("got %v want error containing %q", err, v.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := make([]string, len(ddl.List))
+			for i, stmt := range ddl.List {
+				actual[i] = stmt.SQL()
+			}
+			if diff := cmp.Diff(v.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}